@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const diffContextLines = 3
+const noNewlineMarker = "\\ No newline at end of file"
+
+// Keeps generated files in memory, so all layers are built before the output is touched
+// and the files can be compared with an existing output directory
+type memorySink struct {
+	files map[string][]byte
+	names []string
+}
+
+func newMemorySink() *memorySink {
+	return &memorySink{files: make(map[string][]byte)}
+}
+
+// Two generated files with the same name would silently overwrite each other
+func (s *memorySink) write(name string, content []byte) {
+	if _, ok := s.files[name]; ok {
+		log.Fatalf("Error: %s is generated twice, use a -filename-pattern that gives every layer and format its own file", name)
+	}
+	s.names = append(s.names, name)
+	s.files[name] = content
+}
+
+func (s *memorySink) close() {}
+
+// Write the files to another sink in the order they were generated and close it
+func (s *memorySink) copyTo(sink outputSink) {
+	for _, name := range s.names {
+		sink.write(name, s.files[name])
+	}
+	sink.close()
+}
+
+// Check that the output can be compared with -check, only a directory can
+func checkCheckOutput(output string) {
+	if isArchiveOutput(output) {
+		log.Fatal("Error: -check compares with an output directory, not with an archive or stdout: " + output)
+	}
+	if info, err := os.Stat(output); err == nil && !info.IsDir() {
+		log.Fatal("Error: -check compares with an output directory, " + output + " is not a directory")
+	}
+}
+
+// Compare generated files with an output directory, print the differences and report drift
+func checkOutputDir(out io.Writer, dir string, generated map[string][]byte) bool {
+	existing := existingOutputFiles(dir)
+	var names []string
+	for name := range generated {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	drift := false
+	for _, name := range names {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if os.IsNotExist(err) {
+			fmt.Fprintf(out, "Added: %s\n", name)
+			drift = true
+			continue
+		}
+		if err != nil {
+			log.Fatal("Cannot read existing file: ", err)
+		}
+		if diff := unifiedDiff("a/"+name, "b/"+name, string(content), string(generated[name])); diff != "" {
+			fmt.Fprint(out, diff)
+			drift = true
+		}
+	}
+	for _, name := range existing {
+		if _, ok := generated[name]; !ok {
+			fmt.Fprintf(out, "Removed: %s\n", name)
+			drift = true
+		}
+	}
+	return drift
+}
+
+// List the generated files in an output directory, from its manifest or else by walking it
+func existingOutputFiles(dir string) []string {
+	if files := readManifest(dir); files != nil {
+		return files
+	}
+	var files []string
+	err := filepath.Walk(dir, func(fileName string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			name, err := filepath.Rel(dir, fileName)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal("Cannot read output directory: ", err)
+	}
+	return files
+}
+
+// Create a unified diff between two texts, empty when they are equal
+func unifiedDiff(oldName string, newName string, oldText string, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+	ops := diffLines(oldLines, newLines)
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// find the next change and the hunk around it
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+		hunkEnd := end + diffContextLines
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+		writeHunk(&buf, ops[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+	return buf.String()
+}
+
+// A line of a diff: ' ' for unchanged, '-' for removed and '+' for added
+type diffOp struct {
+	kind    byte
+	line    string
+	oldLine int
+	newLine int
+}
+
+func writeHunk(buf *strings.Builder, ops []diffOp) {
+	oldStart, newStart, oldCount, newCount := 0, 0, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if oldCount == 0 {
+				oldStart = op.oldLine
+			}
+			oldCount++
+		}
+		if op.kind != '-' {
+			if newCount == 0 {
+				newStart = op.newLine
+			}
+			newCount++
+		}
+	}
+	if oldCount == 0 {
+		oldStart = ops[0].oldLine - 1
+	}
+	if newCount == 0 {
+		newStart = ops[0].newLine - 1
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, op := range ops {
+		buf.WriteByte(op.kind)
+		buf.WriteString(op.line)
+		buf.WriteByte('\n')
+	}
+}
+
+// Calculate line based edit operations using the longest common subsequence
+func diffLines(oldLines []string, newLines []string) []diffOp {
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{' ', oldLines[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', oldLines[i], i + 1, j + 1})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', newLines[j], i + 1, j + 1})
+			j++
+		}
+	}
+	return ops
+}
+
+// Split a text in lines, a last line without a newline carries the marker of unified diffs,
+// so texts that only differ in the final newline still give a hunk
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if !strings.HasSuffix(text, "\n") {
+		lines[len(lines)-1] += "\n" + noNewlineMarker
+	}
+	return lines
+}
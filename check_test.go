@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_unifiedDiff(t *testing.T) {
+	const expectedResult = "--- a/layer.html\n+++ b/layer.html\n@@ -1,3 +1,3 @@\n <tr>\n-<th>oud</th>\n+<th>nieuw</th>\n </tr>\n"
+	result := unifiedDiff("a/layer.html", "b/layer.html", "<tr>\n<th>oud</th>\n</tr>\n", "<tr>\n<th>nieuw</th>\n</tr>\n")
+	if result != expectedResult {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expectedResult)
+	}
+	const expectedNewlineResult = "--- a/layer.html\n+++ b/layer.html\n@@ -1,2 +1,2 @@\n <tr>\n-</tr>\n+</tr>\n\\ No newline at end of file\n"
+	result = unifiedDiff("a/layer.html", "b/layer.html", "<tr>\n</tr>\n", "<tr>\n</tr>")
+	if result != expectedNewlineResult {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expectedNewlineResult)
+	}
+	if unifiedDiff("a", "b", "same\n", "same\n") != "" {
+		t.Error("Equal texts should give an empty diff.")
+	}
+}
+
+func Test_checkOutputDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := openOutputSink(dir, false)
+	sink.write("layer1.html", []byte("layer1\n"))
+	sink.write("layer2.html", []byte("layer2\n"))
+	sink.close()
+
+	var out bytes.Buffer
+	if checkOutputDir(&out, dir, map[string][]byte{"layer1.html": []byte("layer1\n"), "layer2.html": []byte("layer2\n")}) {
+		t.Errorf("Unexpected drift:\n%s", out.String())
+	}
+	out.Reset()
+	if !checkOutputDir(&out, dir, map[string][]byte{"layer1.html": []byte("layer1\n"), "layer3.html": []byte("layer3\n")}) {
+		t.Error("Drift was not detected.")
+	}
+	const expectedResult = "Added: layer3.html\nRemoved: layer2.html\n"
+	if out.String() != expectedResult {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", out.String(), expectedResult)
+	}
+}
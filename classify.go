@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// Classes of columns, include and exclude rules target them with class:<name>
+const (
+	classPrimaryKey      = "primary-key"
+	classForeignKey      = "foreign-key"
+	classTechnical       = "technical"
+	classGeometryDerived = "geometry-derived"
+	classDescriptive     = "descriptive"
+)
+
+const classSelectorPrefix = "class:"
+
+// What the classifiers know about a column: its name, declared type and keys from the table definition
+type classifierInput struct {
+	Name       string
+	Type       string
+	PrimaryKey bool
+	ForeignKey bool
+}
+
+// Classifier that returns the class of a column, or nothing to leave the column to the next classifier
+type columnClassifier func(column classifierInput) string
+
+// Classifiers in the order they are tried, columns no classifier claims are descriptive.
+// Technical names go first, so a fid that is the primary key is technical.
+var columnClassifiers = []columnClassifier{
+	classifyTechnicalName,
+	classifyKeys,
+	classifyGeometryDerivedName,
+	classifyIdentifierName,
+}
+
+// Names of technical columns, compared without separators so tijdstipRegistratie matches tijdstip_registratie
+var technicalColumnNames = map[string]bool{
+	"fid": true, "ogcfid": true, "objectid": true, "gmlid": true, "globalid": true, "guid": true, "uuid": true,
+	"lokaalid": true, "namespace": true, "versie": true, "version": true,
+	"tijdstipregistratie": true, "eindregistratie": true, "lvpublicatiedatum": true,
+	"createdat": true, "updatedat": true, "createdby": true, "updatedby": true,
+	"createduser": true, "createddate": true, "lastediteduser": true, "lastediteddate": true,
+}
+
+// Names of columns with measures of the geometry that GIS software adds
+var geometryDerivedColumnNames = map[string]bool{
+	"shapelen": true, "shapeleng": true, "shapelength": true, "shapearea": true,
+	"starea": true, "stlength": true, "stareashape": true, "stlengthshape": true, "geomarea": true, "geomlength": true,
+}
+
+// Primary keys and declared foreign keys
+func classifyKeys(column classifierInput) string {
+	if column.PrimaryKey {
+		return classPrimaryKey
+	}
+	if column.ForeignKey {
+		return classForeignKey
+	}
+	return ""
+}
+
+func classifyTechnicalName(column classifierInput) string {
+	if technicalColumnNames[normalizedColumnName(column.Name)] {
+		return classTechnical
+	}
+	return ""
+}
+
+func classifyGeometryDerivedName(column classifierInput) string {
+	if geometryDerivedColumnNames[normalizedColumnName(column.Name)] {
+		return classGeometryDerived
+	}
+	return ""
+}
+
+// Integer columns named like a reference to another table, like wijk_id or wijkId, without a declared foreign key
+func classifyIdentifierName(column classifierInput) string {
+	if (strings.HasSuffix(strings.ToLower(column.Name), "_id") || strings.HasSuffix(column.Name, "Id")) &&
+		strings.Contains(strings.ToUpper(column.Type), "INT") {
+		return classForeignKey
+	}
+	return ""
+}
+
+// Lower case name without separators
+func normalizedColumnName(name string) string {
+	return nameSeparatorRegexp.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// Class of a column from the first classifier that claims it
+func classifyColumn(column classifierInput) string {
+	for _, classifier := range columnClassifiers {
+		if class := classifier(column); class != "" {
+			return class
+		}
+	}
+	return classDescriptive
+}
+
+// Classify the columns of a layer and leave out the columns the exclude rules of the flags and config match,
+// unless an include rule matches them too. Rules are column names or classes like class:technical.
+func applyColumnClasses(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	keys := getKeyColumnsFromLayer(layer.Name, geopackage)
+	exclude, include := options.columnRules(layer.Name)
+	layer.Classes = make(map[string]string)
+	var columns []templateColumn
+	for _, column := range layer.Columns {
+		if !column.hasAttributeValue() {
+			columns = append(columns, column)
+			continue
+		}
+		input := classifierInput{Name: column.Name, Type: column.Type, PrimaryKey: keys[column.Name] == classPrimaryKey, ForeignKey: keys[column.Name] == classForeignKey}
+		column.Class = classifyColumn(input)
+		layer.Classes[column.Name] = column.Class
+		if matchesColumnRule(column, exclude) && !matchesColumnRule(column, include) {
+			log.Printf("Column %s of layer %s (%s) is left out by an exclude rule", column.Name, layer.Name, column.Class)
+			layer.Excluded = append(layer.Excluded, column.Name)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	layer.Columns = columns
+}
+
+// Primary key and foreign key columns of a table, from PRAGMA table_info and PRAGMA foreign_key_list
+func getKeyColumnsFromLayer(layer string, geopackage *sql.DB) map[string]string {
+	keys := make(map[string]string)
+	rows, err := geopackage.Query(`SELECT "from" FROM pragma_foreign_key_list(?)`, layer)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		keys[name] = classForeignKey
+	}
+	rows.Close()
+	rows, err = geopackage.Query("SELECT name FROM pragma_table_info(?) WHERE pk > 0", layer)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		keys[name] = classPrimaryKey
+	}
+	return keys
+}
+
+// Whether a column matches one of the rules, by name or by class
+func matchesColumnRule(column templateColumn, rules []string) bool {
+	for _, rule := range rules {
+		if rule == column.Name || rule == classSelectorPrefix+column.Class {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that the class rules name known classes
+func checkColumnRules(rules []string) {
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule, classSelectorPrefix) {
+			continue
+		}
+		switch strings.TrimPrefix(rule, classSelectorPrefix) {
+		case classPrimaryKey, classForeignKey, classTechnical, classGeometryDerived, classDescriptive:
+		default:
+			log.Fatal("Error: unknown column class in rule " + rule)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_classifyColumn(t *testing.T) {
+	tests := map[classifierInput]string{
+		{Name: "fid", Type: "INTEGER", PrimaryKey: true}:    classTechnical,
+		{Name: "putnummer", Type: "TEXT", PrimaryKey: true}: classPrimaryKey,
+		{Name: "fid", Type: "INTEGER"}:                      classTechnical,
+		{Name: "gml_id", Type: "TEXT"}:                      classTechnical,
+		{Name: "tijdstipRegistratie", Type: "DATETIME"}:     classTechnical,
+		{Name: "st_area(shape)", Type: "REAL"}:              classGeometryDerived,
+		{Name: "wijk_id", Type: "INTEGER"}:                  classForeignKey,
+		{Name: "wijkId", Type: "INTEGER"}:                   classForeignKey,
+		{Name: "beheerder", Type: "TEXT", ForeignKey: true}: classForeignKey,
+		{Name: "valid", Type: "INTEGER"}:                    classDescriptive,
+		{Name: "naam", Type: "TEXT"}:                        classDescriptive,
+	}
+	for input, expected := range tests {
+		if class := classifyColumn(input); class != expected {
+			t.Errorf("Class of %+v was %s, expected %s", input, class, expected)
+		}
+	}
+}
+
+func Test_applyColumnClasses(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"CREATE TABLE wijken (id INTEGER PRIMARY KEY, naam TEXT)",
+		"ALTER TABLE putten ADD COLUMN wijk INTEGER REFERENCES wijken(id)",
+		"ALTER TABLE putten ADD COLUMN lokaal_id TEXT",
+		"ALTER TABLE putten ADD COLUMN versie INTEGER",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := &templateConfig{Include: []string{"fid"}, Layers: map[string]layerConfig{"putten": {Exclude: []string{"class:foreign-key", "actief"}}}}
+	options := &templateOptions{exclude: "class:technical, class:primary-key", config: config}
+	layer := buildTemplateLayer("putten", geopackage, []string{"geom"}, options)
+	var names []string
+	for _, column := range layer.Columns {
+		names = append(names, column.Name)
+	}
+	if strings.Join(names, ",") != "fid,naam,diepte,bouwdatum" {
+		t.Errorf("Unexpected columns: %v", names)
+	}
+	if strings.Join(layer.Excluded, ",") != "actief,wijk,lokaal_id,versie" {
+		t.Errorf("Unexpected excluded columns: %v", layer.Excluded)
+	}
+	if layer.Classes["wijk"] != classForeignKey || layer.Classes["fid"] != classTechnical || layer.Classes["naam"] != classDescriptive {
+		t.Errorf("Unexpected classes: %v", layer.Classes)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const maxReportedUnknownCodes = 10
+
+// Code list bound to a column in the configuration file
+type codeListConfig struct {
+	File     string `json:"file"`
+	ShowCode bool   `json:"showCode,omitempty"`
+}
+
+// Labels of the codes of a column, in the order of the code list file
+type codeList struct {
+	Codes    []codeLabel
+	ShowCode bool
+}
+
+// Labels holds the SKOS labels by language, used for the templates made with -lang
+type codeLabel struct {
+	Code   string
+	Label  string
+	Labels map[string]string
+}
+
+// Replace the codes of the columns bound to a code list by their labels
+func applyCodeLists(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	for i, column := range layer.Columns {
+		settings := options.config.column(layer.Name, column.Name)
+		if !column.hasAttributeValue() || settings == nil || settings.CodeList == nil {
+			continue
+		}
+		file := settings.CodeList.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(options.config.dir, file)
+		}
+		language := ""
+		if languages := options.languages(); len(languages) > 0 {
+			language = languages[0]
+		}
+		list := readCodeList(file, language)
+		list.ShowCode = settings.CodeList.ShowCode
+		if geopackage != nil {
+			checkCodeListValues(layer.Name, column.Name, list, geopackage)
+		}
+		layer.Columns[i].CodeList = &list
+	}
+}
+
+// Read a code list from a CSV, JSON or SKOS RDF/XML file, labels in the language are preferred in SKOS
+// and the first label is used without a language
+func readCodeList(file string, language string) codeList {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatal("Cannot read code list: ", err)
+	}
+	var list codeList
+	switch strings.ToLower(path.Ext(file)) {
+	case ".csv":
+		list, err = parseCSVCodeList(content)
+	case ".json":
+		list, err = parseJSONCodeList(content)
+	case ".rdf", ".xml", ".skos":
+		list, err = parseSKOSCodeList(content, language)
+	default:
+		log.Fatalf("Error: unknown code list format %s, use .csv, .json or .rdf", file)
+	}
+	if err != nil {
+		log.Fatalf("Cannot parse code list %s: %v", file, err)
+	}
+	list.Codes = mapserverCodes(list.Codes, "code list "+file)
+	log.Printf("Read %d codes from code list %s", len(list.Codes), file)
+	return list
+}
+
+// Leave out codes with " or ], they can't be used in a MapServer [if]
+func mapserverCodes(codes []codeLabel, source string) []codeLabel {
+	var usable []codeLabel
+	for _, code := range codes {
+		if strings.ContainsAny(code.Code, `"]`) {
+			log.Printf("Code %s in %s can't be used in a MapServer [if] and is left out", code.Code, source)
+			continue
+		}
+		usable = append(usable, code)
+	}
+	return usable
+}
+
+// CSV with the code in the first and the label in the second column, with an optional header row starting with code
+func parseCSVCodeList(content []byte) (codeList, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return codeList{}, err
+	}
+	var list codeList
+	for i, record := range records {
+		if len(record) < 2 || (i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "code")) {
+			continue
+		}
+		list.Codes = append(list.Codes, codeLabel{Code: strings.TrimSpace(record[0]), Label: strings.TrimSpace(record[1])})
+	}
+	return list, nil
+}
+
+// JSON object from code to label, or an array of objects with a code and a label
+func parseJSONCodeList(content []byte) (codeList, error) {
+	var list codeList
+	var codes []struct {
+		Code  json.Number `json:"code"`
+		Label string      `json:"label"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&codes); err == nil {
+		for _, code := range codes {
+			list.Codes = append(list.Codes, codeLabel{Code: code.Code.String(), Label: code.Label})
+		}
+		return list, nil
+	}
+	labels := make(map[string]string)
+	if err := json.Unmarshal(content, &labels); err != nil {
+		return list, err
+	}
+	for code, label := range labels {
+		list.Codes = append(list.Codes, codeLabel{Code: code, Label: label})
+	}
+	sort.Slice(list.Codes, func(i, j int) bool { return list.Codes[i].Code < list.Codes[j].Code })
+	return list, nil
+}
+
+// SKOS concepts in RDF/XML, the notation is the code and the last part of the concept URI when there is none
+func parseSKOSCodeList(content []byte, language string) (codeList, error) {
+	var document struct {
+		Concepts []struct {
+			About     string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+			Notation  string `xml:"http://www.w3.org/2004/02/skos/core# notation"`
+			PrefLabel []struct {
+				Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"http://www.w3.org/2004/02/skos/core# prefLabel"`
+		} `xml:"http://www.w3.org/2004/02/skos/core# Concept"`
+	}
+	var list codeList
+	if err := xml.Unmarshal(content, &document); err != nil {
+		return list, err
+	}
+	for _, concept := range document.Concepts {
+		code := strings.TrimSpace(concept.Notation)
+		if code == "" {
+			code = concept.About[strings.LastIndexAny(concept.About, "/#")+1:]
+		}
+		label := ""
+		labels := make(map[string]string)
+		for _, prefLabel := range concept.PrefLabel {
+			if label == "" || strings.EqualFold(prefLabel.Lang, language) {
+				label = strings.TrimSpace(prefLabel.Value)
+			}
+			if prefLabel.Lang != "" {
+				labels[strings.ToLower(prefLabel.Lang)] = strings.TrimSpace(prefLabel.Value)
+			}
+		}
+		list.Codes = append(list.Codes, codeLabel{Code: code, Label: label, Labels: labels})
+	}
+	return list, nil
+}
+
+// Warn about values in the data that are not in the code list
+func checkCodeListValues(layer string, column string, list codeList, geopackage *sql.DB) {
+	rows, err := geopackage.Query("SELECT DISTINCT " + quoteIdentifier(column) + " FROM " + quoteIdentifier(layer) + " WHERE " + quoteIdentifier(column) + " IS NOT NULL")
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	known := make(map[string]bool)
+	for _, code := range list.Codes {
+		known[code.Code] = true
+	}
+	var unknown []string
+	for rows.Next() {
+		var value interface{}
+		if err = rows.Scan(&value); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		if code := templateValue(value); !known[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) == 0 {
+		return
+	}
+	sort.Strings(unknown)
+	reported := unknown
+	if len(reported) > maxReportedUnknownCodes {
+		reported = reported[:maxReportedUnknownCodes]
+	}
+	log.Printf("Warning: column %s of layer %s has %d values that are not in the code list: %s",
+		column, layer, len(unknown), strings.Join(reported, ", "))
+}
+
+// Label of a code, with the code when the code list is configured to show it
+func (l codeList) text(code codeLabel) string {
+	if l.ShowCode {
+		return code.Label + " (" + code.Code + ")"
+	}
+	return code.Label
+}
+
+// Copy of a code list with the SKOS labels in a language, lists without labels in the language stay the same
+func (l *codeList) translate(language string) *codeList {
+	translated := codeList{ShowCode: l.ShowCode}
+	for _, code := range l.Codes {
+		if label, ok := code.Labels[strings.ToLower(language)]; ok {
+			code.Label = label
+		}
+		translated.Codes = append(translated.Codes, code)
+	}
+	return &translated
+}
+
+// MapServer [if] chain showing the label of the code of a column, values that are not in the list are shown
+// as they are inside an [if] per code that doesn't match
+func mapserverCodeList(column templateColumn, escape string) string {
+	var labels, start, end strings.Builder
+	for _, code := range column.CodeList.Codes {
+		labels.WriteString(`[if name="` + column.Name + `" oper="eq" value="` + code.Code + `"]` + escapeStatic(column.CodeList.text(code), escape) + `[/if]`)
+		start.WriteString(`[if name="` + column.Name + `" oper="neq" value="` + code.Code + `"]`)
+		end.WriteString(`[/if]`)
+	}
+	return labels.String() + start.String() + mapserverValue(column.unformatted(), escape) + end.String()
+}
+
+// FreeMarker hash lookup showing the label of the code of a column, or the code when it's not in the list
+func freeMarkerCodeList(column templateColumn) string {
+	var entries []string
+	for _, code := range column.CodeList.Codes {
+		entries = append(entries, `"`+freeMarkerString(code.Code)+`": "`+freeMarkerString(column.CodeList.text(code))+`"`)
+	}
+	value := `((feature["` + freeMarkerString(column.Name) + `"].value)!"")`
+	return `${(({` + strings.Join(entries, ", ") + `})[` + value + `]!` + value + `)?html}`
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testCodes = []codeLabel{{Code: "1", Label: "In gebruik"}, {Code: "2", Label: "Buiten gebruik"}}
+
+func Test_parseCodeLists(t *testing.T) {
+	csvList, err := parseCSVCodeList([]byte("code,label\n1,In gebruik\n2,\"Buiten gebruik\"\n"))
+	if err != nil || !reflect.DeepEqual(csvList.Codes, testCodes) {
+		t.Errorf("CSV code list was %v (%v), expected %v", csvList.Codes, err, testCodes)
+	}
+	jsonList, err := parseJSONCodeList([]byte(`[{"code": 1, "label": "In gebruik"}, {"code": "2", "label": "Buiten gebruik"}]`))
+	if err != nil || !reflect.DeepEqual(jsonList.Codes, testCodes) {
+		t.Errorf("JSON code list was %v (%v), expected %v", jsonList.Codes, err, testCodes)
+	}
+	jsonList, err = parseJSONCodeList([]byte(`{"2": "Buiten gebruik", "1": "In gebruik"}`))
+	if err != nil || !reflect.DeepEqual(jsonList.Codes, testCodes) {
+		t.Errorf("JSON object code list was %v (%v), expected %v", jsonList.Codes, err, testCodes)
+	}
+	skos := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:skos="http://www.w3.org/2004/02/skos/core#">
+	<skos:Concept rdf:about="https://registry.example/status/1">
+		<skos:notation>1</skos:notation>
+		<skos:prefLabel xml:lang="en">In use</skos:prefLabel>
+		<skos:prefLabel xml:lang="nl">In gebruik</skos:prefLabel>
+	</skos:Concept>
+	<skos:Concept rdf:about="https://registry.example/status/2">
+		<skos:prefLabel xml:lang="nl">Buiten gebruik</skos:prefLabel>
+	</skos:Concept>
+</rdf:RDF>`
+	skosList, err := parseSKOSCodeList([]byte(skos), "nl")
+	skosCodes := []codeLabel{
+		{Code: "1", Label: "In gebruik", Labels: map[string]string{"en": "In use", "nl": "In gebruik"}},
+		{Code: "2", Label: "Buiten gebruik", Labels: map[string]string{"nl": "Buiten gebruik"}},
+	}
+	if err != nil || !reflect.DeepEqual(skosList.Codes, skosCodes) {
+		t.Errorf("SKOS code list was %v (%v), expected %v", skosList.Codes, err, skosCodes)
+	}
+	translated := skosList.translate("en")
+	if translated.Codes[0].Label != "In use" || translated.Codes[1].Label != "Buiten gebruik" {
+		t.Errorf("English SKOS code list was %v", translated.Codes)
+	}
+}
+
+func Test_applyCodeLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codelist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = ioutil.WriteFile(filepath.Join(dir, "status.csv"), []byte("1,In gebruik\n2,Buiten gebruik & gesloten\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &templateConfig{dir: dir, Layers: map[string]layerConfig{"putten": {Columns: map[string]columnConfig{
+		"status": {CodeList: &codeListConfig{File: "status.csv", ShowCode: true}},
+	}}}}
+	layer := newTemplateLayer("putten", []string{"naam", "status"}, nil)
+	applyCodeLists(&layer, nil, &templateOptions{config: config})
+
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", []templateFeature{{Values: map[string]string{"naam": "Put 1", "status": "2"}}})
+	if expected := "<td>Buiten gebruik &amp; gesloten (2)</td>"; !strings.Contains(rendered, expected) {
+		t.Errorf("Rendered template doesn't contain %s:\n%s", expected, rendered)
+	}
+	expected := `${(({"1": "In gebruik (1)", "2": "Buiten gebruik & gesloten (2)"})[((feature["status"].value)!"")]!((feature["status"].value)!""))?html}`
+	if result := freeMarkerValue(layer.Columns[1]); result != expected {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expected)
+	}
+	rendered = renderMapserverTemplate(generateJSON(layer, false), "putten", []templateFeature{{Values: map[string]string{"naam": "Put 1", "status": "2"}}})
+	if expected := `"status": "Buiten gebruik & gesloten (2)"`; !strings.Contains(rendered, expected) {
+		t.Errorf("Rendered JSON doesn't contain %s:\n%s", expected, rendered)
+	}
+	unknown := []templateFeature{{Values: map[string]string{"naam": "Put 1", "status": "9 \"onbekend\""}}}
+	rendered = renderMapserverTemplate(generateHTML(layer).String(), "putten", unknown)
+	if expected := "<td>9 &#34;onbekend&#34;</td>"; !strings.Contains(rendered, expected) {
+		t.Errorf("Rendered template doesn't show the unknown code %s:\n%s", expected, rendered)
+	}
+	rendered = renderMapserverTemplate(generateJSON(layer, false), "putten", unknown)
+	if expected := `"status": "9 \"onbekend\""`; !strings.Contains(rendered, expected) {
+		t.Errorf("Rendered JSON doesn't show the unknown code %s:\n%s", expected, rendered)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import "log"
+
+// Display field combining columns, configured per layer in the configuration file
+type computedConfig struct {
+	Header      string `json:"header"`
+	Format      string `json:"format"`
+	Description string `json:"description,omitempty"`
+}
+
+// Part of a computed field: the value of a column with the texts around it. The texts are left out when the value is empty,
+// so separators disappear with missing values. Only the last part has a text after the value.
+type computedPart struct {
+	Prefix string
+	Column *templateColumn
+	Suffix string
+}
+
+// Replace the columns used by the computed fields of a layer by the fields, at the position of the first column used
+func applyComputedFields(layer *templateLayer, tableColumns []columnInfo, config *templateConfig) {
+	if config == nil {
+		return
+	}
+	for _, computed := range config.Layers[layer.Name].Computed {
+		if computed.Header == "" || computed.Format == "" {
+			log.Fatalf("Error: computed field of layer %s needs a header and a format", layer.Name)
+		}
+		field := templateColumn{Header: computed.Header, Description: computed.Description}
+		used := make(map[string]bool)
+		last := 0
+		for _, match := range linkPlaceholderRegexp.FindAllStringSubmatchIndex(computed.Format, -1) {
+			name := computed.Format[match[2]:match[3]]
+			column, ok := layer.computedColumn(name, tableColumns)
+			if !ok {
+				log.Fatalf("Error: computed field %s of layer %s uses unknown column {%s}", computed.Header, layer.Name, name)
+			}
+			field.Parts = append(field.Parts, computedPart{Prefix: computed.Format[last:match[0]], Column: &column})
+			used[name] = true
+			last = match[1]
+		}
+		if len(field.Parts) == 0 {
+			log.Fatalf("Error: computed field %s of layer %s doesn't use any {column}", computed.Header, layer.Name)
+		}
+		field.Parts[len(field.Parts)-1].Suffix = computed.Format[last:]
+		var columns []templateColumn
+		inserted := false
+		for _, column := range layer.Columns {
+			if used[column.Name] && column.hasAttributeValue() {
+				if !inserted {
+					columns = append(columns, field)
+					inserted = true
+				}
+				continue
+			}
+			columns = append(columns, column)
+		}
+		if !inserted {
+			columns = append(columns, field)
+		}
+		layer.Columns = columns
+	}
+}
+
+// Column used in a computed field as it is shown by the layer, or as it is stored when the layer hides it
+func (l templateLayer) computedColumn(name string, tableColumns []columnInfo) (templateColumn, bool) {
+	for _, column := range l.Columns {
+		if column.Name == name && column.hasAttributeValue() {
+			return column, true
+		}
+	}
+	for _, excluded := range l.PersonalData {
+		if excluded == name {
+			log.Fatalf("Error: computed field of layer %s uses column %s with personal data, add it to allowPersonalData in the config to publish it", l.Name, name)
+		}
+	}
+	for _, column := range tableColumns {
+		if column.Name == name {
+			return templateColumn{Name: name, Header: name, Type: column.Type}, true
+		}
+	}
+	return templateColumn{}, false
+}
+
+// MapServer expression for a computed field: the texts and [item] placeholders of its parts after each other
+func mapserverComputed(column templateColumn, escape string) string {
+	var value string
+	for _, part := range column.Parts {
+		if part.Prefix != "" || part.Suffix != "" {
+			value += `[if name="` + part.Column.Name + `" oper="isset"]` + escapeStatic(part.Prefix, escape) + mapserverValue(*part.Column, escape) +
+				escapeStatic(part.Suffix, escape) + `[/if]`
+			continue
+		}
+		value += mapserverValue(*part.Column, escape)
+	}
+	return value
+}
+
+// FreeMarker expression for a computed field
+func freeMarkerComputed(column templateColumn) string {
+	var value string
+	for _, part := range column.Parts {
+		if part.Prefix != "" || part.Suffix != "" {
+			value += `<#if (feature["` + freeMarkerString(part.Column.Name) + `"].value)?has_content>` + freeMarkerText(part.Prefix) + freeMarkerValue(*part.Column) +
+				freeMarkerText(part.Suffix) + `</#if>`
+			continue
+		}
+		value += freeMarkerValue(*part.Column)
+	}
+	return value
+}
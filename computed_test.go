@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_applyComputedFields(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	hidden := true
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {
+		Columns: map[string]columnConfig{"bouwdatum": {Hidden: &hidden}},
+		Computed: []computedConfig{
+			{Header: "Put", Format: "{naam} ({diepte} m)"},
+			{Header: "Gebouwd", Format: "{bouwdatum}"},
+		},
+	}}}
+	options := &templateOptions{config: config, locale: defaultLocale}
+	layer := buildTemplateLayer("putten", geopackage, []string{"geom"}, options)
+
+	var headers []string
+	for _, column := range layer.Columns {
+		headers = append(headers, column.Header)
+	}
+	if strings.Join(headers, ",") != "fid,Put,actief,Gebouwd" {
+		t.Fatalf("Unexpected columns: %v", headers)
+	}
+	features := getSampleFeatures(layer, geopackage, 2)
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", features)
+	for _, expected := range []string{"<td>Put 1 (2.50 m)</td>", "<td>Put 2</td>", "<td>2020-01-05</td>"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Rendered template doesn't contain %s:\n%s", expected, rendered)
+		}
+	}
+	if text := renderMapserverTemplate(generateText(layer), "putten", features); !strings.Contains(text, "Put    : Put 1 (2.50 m)\n") {
+		t.Errorf("Unexpected text:\n%s", text)
+	}
+	validateJSONTemplate(layer, generateJSON(layer, false))
+	ftl := generateFreeMarkerContent(layer).String()
+	expected := `${((feature["naam"].value)!"")?html}<#if (feature["diepte"].value)?has_content> (${((feature["diepte"].rawValue?string("0.00"))!"")?html} m)</#if>`
+	if !strings.Contains(ftl, expected) {
+		t.Errorf("content.ftl doesn't contain %s:\n%s", expected, ftl)
+	}
+}
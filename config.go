@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+)
+
+// Configuration file with settings per layer and column, given with -config
+type templateConfig struct {
+	Locale            string                       `json:"locale"`
+	Layers            map[string]layerConfig       `json:"layers"`
+	AllowPersonalData []string                     `json:"allowPersonalData,omitempty"`
+	Exclude           []string                     `json:"exclude,omitempty"`
+	Include           []string                     `json:"include,omitempty"`
+	Abbreviations     map[string]string            `json:"abbreviations,omitempty"`
+	Translations      map[string]translationConfig `json:"translations,omitempty"`
+	dir               string
+}
+
+// Settings of a layer in the configuration file
+type layerConfig struct {
+	Order    []string                `json:"order,omitempty"`
+	Columns  map[string]columnConfig `json:"columns"`
+	Computed []computedConfig        `json:"computed,omitempty"`
+	Exclude  []string                `json:"exclude,omitempty"`
+	Include  []string                `json:"include,omitempty"`
+}
+
+// Settings of a column in the configuration file, the value format settings are on the column itself
+type columnConfig struct {
+	valueFormat
+	Header   string          `json:"header,omitempty"`
+	Hidden   *bool           `json:"hidden,omitempty"`
+	Link     *linkFormat     `json:"link,omitempty"`
+	CodeList *codeListConfig `json:"codeList,omitempty"`
+	JSON     *jsonConfig     `json:"json,omitempty"`
+}
+
+// Read a JSON configuration file, unknown settings are an error so typos don't go unnoticed
+func readConfig(path string) *templateConfig {
+	config := &templateConfig{}
+	if path == "" {
+		return config
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatal("Cannot read config: ", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(config); err != nil {
+		log.Fatalf("Cannot parse config %s: %v", path, err)
+	}
+	config.dir = filepath.Dir(path)
+	return config
+}
+
+// Settings of a column in the configuration file, nil when there are none
+func (c *templateConfig) column(layer string, column string) *columnConfig {
+	if c == nil {
+		return nil
+	}
+	if settings, ok := c.Layers[layer].Columns[column]; ok {
+		return &settings
+	}
+	return nil
+}
+
+// Whether the configuration file shows a column explicitly, overriding a hidden field in the QGIS style
+func (c *templateConfig) shows(layer string, column string) bool {
+	settings := c.column(layer, column)
+	return settings != nil && settings.Hidden != nil && !*settings.Hidden
+}
+
+// Whether the configuration file allows publishing a column that looks like personal data, as column or layer.column
+func (c *templateConfig) allowsPersonalData(layer string, column string) bool {
+	if c == nil {
+		return false
+	}
+	for _, allowed := range c.AllowPersonalData {
+		if allowed == column || allowed == layer+"."+column {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply the headers, hidden columns and order of the configuration file to a layer
+func applyColumnConfig(layer *templateLayer, config *templateConfig) {
+	var columns []templateColumn
+	for _, column := range layer.Columns {
+		settings := config.column(layer.Name, column.Name)
+		if settings != nil && settings.Hidden != nil && *settings.Hidden {
+			continue
+		}
+		if settings != nil && settings.Header != "" {
+			column.Header = settings.Header
+		}
+		columns = append(columns, column)
+	}
+	layer.Columns = columns
+	if config != nil {
+		layer.orderColumns(config.Layers[layer.Name].Order)
+	}
+}
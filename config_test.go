@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_readConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	content := `{"locale": "en-GB", "layers": {"putten": {"columns": {"diepte": {"precision": 1}}}}}`
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := readConfig(path)
+	if config.Locale != "en-GB" {
+		t.Errorf("Locale was %s, expected en-GB", config.Locale)
+	}
+	if format := config.column("putten", "diepte"); format == nil || *format.Precision != 1 {
+		t.Errorf("Column format was %v, expected precision 1", format)
+	}
+	if format := config.column("putten", "naam"); format != nil {
+		t.Errorf("Column format was %v, expected none", format)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// Whether the empty values of a column can be hidden or replaced, fixed texts and MapServer tags are always shown
+func (c templateColumn) hasAttributeValue() bool {
+	return c.Name != "" && c.Static == "" && c.Tag == ""
+}
+
+// MapServer [if] around the header and value of a column that is left out when the value is empty
+func (l templateLayer) emptyCondition(column templateColumn) (string, string) {
+	if !l.HideEmpty || !column.hasAttributeValue() {
+		return "", ""
+	}
+	return `[if name="` + column.Name + `" oper="neq" value=""]`, "[/if]"
+}
+
+// MapServer HTML for the value of a column, with the placeholder when the value is empty
+func (l templateLayer) htmlValue(column templateColumn) string {
+	value := mapserverValue(column, "html")
+	if l.EmptyPlaceholder == "" || !column.hasAttributeValue() {
+		return value
+	}
+	return `[if name="` + column.Name + `" oper="eq" value=""]` + mapserverText(l.EmptyPlaceholder) + `[/if]` +
+		`[if name="` + column.Name + `" oper="neq" value=""]` + value + `[/if]`
+}
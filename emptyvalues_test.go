@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testEmptyValuesLayer(layout string) templateLayer {
+	layer := newTemplateLayer("putten", []string{"naam", "diepte"}, nil)
+	layer.Columns = append(layer.Columns, templateColumn{Header: "bron", Static: "PDOK"})
+	layer.Layout = layout
+	return layer
+}
+
+var testEmptyValuesFeature = []templateFeature{{Values: map[string]string{"naam": "Put 1", "diepte": ""}}}
+
+func Test_generateHTML_hideEmpty(t *testing.T) {
+	tests := map[string][]string{
+		"horizontal": {"<th>naam</th>\n\t\t\t\t\n\t\t\t\t<th>bron</th>\n", "<td>Put 1</td>\n\t\t\t\t\n\t\t\t\t<td>PDOK</td>\n"},
+		"vertical":   {"<th>naam</th>\n\t\t\t\t<td>Put 1</td>\n\t\t\t</tr>\n\t\t\t\n\t\t\t<tr>\n\t\t\t\t<th>bron</th>\n\t\t\t\t<td>PDOK</td>\n"},
+	}
+	for layout, expected := range tests {
+		layer := testEmptyValuesLayer(layout)
+		layer.HideEmpty = true
+		rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", testEmptyValuesFeature)
+		for _, value := range expected {
+			if !strings.Contains(rendered, value) {
+				t.Errorf("Rendered %s template doesn't contain %q:\n%s", layout, value, rendered)
+			}
+		}
+		if strings.Contains(rendered, "diepte") {
+			t.Errorf("Rendered %s template contains the empty attribute:\n%s", layout, rendered)
+		}
+	}
+}
+
+func Test_generateHTML_emptyPlaceholder(t *testing.T) {
+	for _, layout := range []string{"horizontal", "vertical"} {
+		layer := testEmptyValuesLayer(layout)
+		layer.EmptyPlaceholder = "–"
+		rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", testEmptyValuesFeature)
+		for _, value := range []string{"<th>diepte</th>", "<td>Put 1</td>", "<td>–</td>", "<td>PDOK</td>"} {
+			if !strings.Contains(rendered, value) {
+				t.Errorf("Rendered %s template doesn't contain %q:\n%s", layout, value, rendered)
+			}
+		}
+	}
+}
+
+func Test_generateHTML_emptyPlaceholderBrackets(t *testing.T) {
+	layer := testEmptyValuesLayer("vertical")
+	layer.EmptyPlaceholder = "[geen]"
+	template := generateHTML(layer).String()
+	if strings.Contains(template, "[geen]") {
+		t.Errorf("Template contains the placeholder as a MapServer tag:\n%s", template)
+	}
+	rendered := renderMapserverTemplate(template, "putten", testEmptyValuesFeature)
+	if !strings.Contains(rendered, "<td>&#91;geen&#93;</td>") {
+		t.Errorf("Rendered template doesn't contain the escaped placeholder:\n%s", rendered)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"log"
+	"strings"
+)
+
+// ISO 19110 feature catalogue, elements are matched on their local name so the 2005 and 2016 schemas both work
+type featureCatalogue struct {
+	FeatureTypes []catalogueFeatureType `xml:"featureType>FC_FeatureType"`
+}
+
+type catalogueFeatureType struct {
+	TypeName   catalogueText        `xml:"typeName"`
+	Attributes []catalogueAttribute `xml:"carrierOfCharacteristics>FC_FeatureAttribute"`
+}
+
+type catalogueAttribute struct {
+	MemberName   catalogueText `xml:"memberName"`
+	Designation  catalogueText `xml:"designation"`
+	Definition   catalogueText `xml:"definition"`
+	Unit         catalogueUnit `xml:"valueMeasurementUnit"`
+	ListedValues []struct {
+		Label catalogueText `xml:"label"`
+		Code  catalogueText `xml:"code"`
+	} `xml:"listedValue>FC_ListedValue"`
+}
+
+// Text of a catalogue element, either directly or in a gco:CharacterString or gco:LocalName
+type catalogueText struct {
+	Value           string `xml:",chardata"`
+	CharacterString string `xml:"CharacterString"`
+	LocalName       string `xml:"LocalName"`
+	ScopedName      string `xml:"ScopedName"`
+}
+
+func (t catalogueText) String() string {
+	for _, value := range []string{t.CharacterString, t.LocalName, t.ScopedName, t.Value} {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// Unit of measure of an attribute, a GML unit definition
+type catalogueUnit struct {
+	Definitions []struct {
+		CatalogSymbol string `xml:"catalogSymbol"`
+		Identifier    string `xml:"identifier"`
+		Name          string `xml:"name"`
+	} `xml:",any"`
+}
+
+func (u catalogueUnit) String() string {
+	for _, definition := range u.Definitions {
+		for _, value := range []string{definition.CatalogSymbol, definition.Identifier, definition.Name} {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// Read the attributes of a layer from the feature catalogues in gpkg_metadata. Catalogues referenced from the table
+// or its columns are used, and catalogues for the whole Geopackage when they describe a feature type with the name of the layer.
+func getFeatureCatalogueForLayer(layer string, geopackage *sql.DB) map[string]catalogueAttribute {
+	var table string
+	err := geopackage.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'gpkg_metadata_reference'").Scan(&table)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	rows, err := geopackage.Query("SELECT DISTINCT r.reference_scope, m.metadata FROM gpkg_metadata_reference r JOIN gpkg_metadata m ON m.id = r.md_file_id "+
+		"WHERE (r.reference_scope IN ('table', 'column') AND r.table_name = ?) OR r.reference_scope = 'geopackage'", layer)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	attributes := make(map[string]catalogueAttribute)
+	for rows.Next() {
+		var scope, metadata string
+		if err = rows.Scan(&scope, &metadata); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		if !strings.Contains(metadata, "FC_FeatureCatalogue") {
+			continue
+		}
+		var catalogue featureCatalogue
+		if err = xml.Unmarshal([]byte(metadata), &catalogue); err != nil {
+			log.Printf("Warning: cannot parse the feature catalogue of layer %s: %v", layer, err)
+			continue
+		}
+		for _, featureType := range catalogue.FeatureTypes {
+			if featureType.TypeName.String() != layer && (scope == "geopackage" || len(catalogue.FeatureTypes) > 1) {
+				continue
+			}
+			for _, attribute := range featureType.Attributes {
+				attributes[attribute.MemberName.String()] = attribute
+			}
+		}
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	log.Printf("Using the feature catalogue of layer %s for headers, descriptions, units and listed values", layer)
+	return attributes
+}
+
+// Use the designations, definitions, units and listed values of a feature catalogue for the columns of a layer
+func applyFeatureCatalogue(layer *templateLayer, attributes map[string]catalogueAttribute, geopackage *sql.DB) {
+	for i, column := range layer.Columns {
+		attribute, ok := attributes[column.Name]
+		if !ok || !column.hasAttributeValue() {
+			continue
+		}
+		if designation := attribute.Designation.String(); designation != "" {
+			layer.Columns[i].Header = designation
+		}
+		layer.Columns[i].Description = attribute.Definition.String()
+		layer.Columns[i].Unit = attribute.Unit.String()
+		if len(attribute.ListedValues) > 0 {
+			var list codeList
+			for _, value := range attribute.ListedValues {
+				code := value.Code.String()
+				if code == "" {
+					code = value.Label.String()
+				}
+				list.Codes = append(list.Codes, codeLabel{Code: code, Label: value.Label.String()})
+			}
+			list.Codes = mapserverCodes(list.Codes, "the listed values of attribute "+column.Name+" in the feature catalogue")
+			if geopackage != nil {
+				checkCodeListValues(layer.Name, column.Name, list, geopackage)
+			}
+			layer.Columns[i].CodeList = &list
+		}
+	}
+}
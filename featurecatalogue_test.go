@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFeatureCatalogue = `<?xml version="1.0" encoding="UTF-8"?>
+<gfc:FC_FeatureCatalogue xmlns:gfc="http://www.isotc211.org/2005/gfc" xmlns:gco="http://www.isotc211.org/2005/gco" xmlns:gml="http://www.opengis.net/gml/3.2">
+	<gfc:featureType>
+		<gfc:FC_FeatureType>
+			<gfc:typeName><gco:LocalName>putten</gco:LocalName></gfc:typeName>
+			<gfc:carrierOfCharacteristics>
+				<gfc:FC_FeatureAttribute>
+					<gfc:memberName><gco:LocalName>diepte</gco:LocalName></gfc:memberName>
+					<gfc:definition><gco:CharacterString>Diepte van de put ten opzichte van "maaiveld"</gco:CharacterString></gfc:definition>
+					<gfc:valueMeasurementUnit>
+						<gml:UnitDefinition gml:id="meter"><gml:identifier codeSpace="">m</gml:identifier></gml:UnitDefinition>
+					</gfc:valueMeasurementUnit>
+				</gfc:FC_FeatureAttribute>
+			</gfc:carrierOfCharacteristics>
+			<gfc:carrierOfCharacteristics>
+				<gfc:FC_FeatureAttribute>
+					<gfc:memberName><gco:LocalName>actief</gco:LocalName></gfc:memberName>
+					<gfc:designation><gco:CharacterString>In gebruik &amp; &lt;actief&gt; ${x}</gco:CharacterString></gfc:designation>
+					<gfc:listedValue>
+						<gfc:FC_ListedValue>
+							<gfc:label><gco:CharacterString>Ja</gco:CharacterString></gfc:label>
+							<gfc:code><gco:CharacterString>1</gco:CharacterString></gfc:code>
+						</gfc:FC_ListedValue>
+					</gfc:listedValue>
+					<gfc:listedValue>
+						<gfc:FC_ListedValue>
+							<gfc:label><gco:CharacterString>Nee</gco:CharacterString></gfc:label>
+							<gfc:code><gco:CharacterString>0</gco:CharacterString></gfc:code>
+						</gfc:FC_ListedValue>
+					</gfc:listedValue>
+				</gfc:FC_FeatureAttribute>
+			</gfc:carrierOfCharacteristics>
+		</gfc:FC_FeatureType>
+	</gfc:featureType>
+</gfc:FC_FeatureCatalogue>`
+
+func Test_applyFeatureCatalogue(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"CREATE TABLE gpkg_metadata (id INTEGER PRIMARY KEY AUTOINCREMENT, md_scope TEXT NOT NULL DEFAULT 'dataset', md_standard_uri TEXT NOT NULL, mime_type TEXT NOT NULL DEFAULT 'text/xml', metadata TEXT NOT NULL DEFAULT '')",
+		"CREATE TABLE gpkg_metadata_reference (reference_scope TEXT NOT NULL, table_name TEXT, column_name TEXT, row_id_value INTEGER, timestamp DATETIME, md_file_id INTEGER NOT NULL, md_parent_id INTEGER)",
+		"INSERT INTO gpkg_metadata_reference VALUES ('table', 'putten', NULL, NULL, NULL, 1, NULL)",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := geopackage.Exec("INSERT INTO gpkg_metadata (md_scope, md_standard_uri, metadata) VALUES ('featureType', 'http://www.isotc211.org/2005/gfc', ?)", testFeatureCatalogue); err != nil {
+		t.Fatal(err)
+	}
+	attributes := getFeatureCatalogueForLayer("putten", geopackage)
+	layer := newTemplateLayer("putten", []string{"naam", "diepte", "actief"}, nil)
+	applyFeatureCatalogue(&layer, attributes, geopackage)
+
+	var headers []string
+	for _, column := range layer.Columns {
+		headers = append(headers, column.Header)
+	}
+	if expected := []string{"naam", "diepte", "In gebruik & <actief> ${x}"}; !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Headers were %v, expected %v", headers, expected)
+	}
+	if expected := []codeLabel{{Code: "1", Label: "Ja"}, {Code: "0", Label: "Nee"}}; layer.Columns[2].CodeList == nil || !reflect.DeepEqual(layer.Columns[2].CodeList.Codes, expected) {
+		t.Errorf("Code list was %v, expected %v", layer.Columns[2].CodeList, expected)
+	}
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", []templateFeature{{Values: map[string]string{"naam": "Put 1", "diepte": "2.5", "actief": "1"}}})
+	for _, expected := range []string{`<th title="Diepte van de put ten opzichte van &#34;maaiveld&#34;">diepte</th>`, "<td>2.5 m</td>", "<td>Ja</td>", "<th>In gebruik &amp; &lt;actief&gt; ${x}</th>"} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Rendered template doesn't contain %s:\n%s", expected, rendered)
+		}
+	}
+	if header := "<th>In gebruik &amp; &lt;actief&gt; &#36;{x}</th>"; !strings.Contains(generateFreeMarkerContent(layer).String(), header) {
+		t.Errorf("Template doesn't contain the escaped header %s:\n%s", header, generateFreeMarkerContent(layer).String())
+	}
+	expected := `${((feature["diepte"].value)!"")?html}<#if (feature["diepte"].value)?has_content> m</#if>`
+	if result := freeMarkerValue(layer.Columns[1]); result != expected {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expected)
+	}
+}
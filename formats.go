@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+const defaultFormats = "html"
+
+// Names and placement of the generated files
+type outputNaming struct {
+	pattern   string
+	dataset   string
+	workspace string
+	store     string
+	language  string
+}
+
+// File generated for a layer, the name is relative to the output
+type generatedFile struct {
+	name    string
+	content []byte
+}
+
+// Output format, generating the files of a layer
+type templateFormat func(layer templateLayer, naming outputNaming) []generatedFile
+
+// Output formats by name
+var templateFormats = map[string]templateFormat{
+	"html":      htmlFormat,
+	"geoserver": geoServerFormat,
+	"json":      jsonFormat,
+	"geojson":   geoJSONFormat,
+	"text":      textFormat,
+	"csv":       csvFormat,
+	"xml":       xmlFormat,
+}
+
+// MapServer HTML template
+func htmlFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	fileName := outputFileName(naming.pattern, naming.dataset, layer.Name, "html")
+	files := []generatedFile{{name: fileName, content: generateHTML(layer).Bytes()}}
+	return append(files, relationFiles(layer, naming)...)
+}
+
+// Parse a comma separated list of output formats
+func parseFormats(param string) []string {
+	var formats []string
+	for _, format := range strings.Split(param, ",") {
+		format = strings.TrimSpace(format)
+		if format == "" {
+			continue
+		}
+		if _, ok := templateFormats[format]; !ok {
+			log.Fatalf("Error: unknown format %s, supported formats are: %s", format, strings.Join(formatNames(), ", "))
+		}
+		formats = append(formats, format)
+	}
+	if formats == nil {
+		log.Fatal("Error: at least one format is required")
+	}
+	return formats
+}
+
+func formatNames() []string {
+	var names []string
+	for name := range templateFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
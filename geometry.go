@@ -0,0 +1,447 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// WKB geometry types
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+var geometryTypeNames = map[uint32]string{
+	wkbPoint:              "Point",
+	wkbLineString:         "LineString",
+	wkbPolygon:            "Polygon",
+	wkbMultiPoint:         "MultiPoint",
+	wkbMultiLineString:    "MultiLineString",
+	wkbMultiPolygon:       "MultiPolygon",
+	wkbGeometryCollection: "GeometryCollection",
+}
+
+type point struct {
+	X float64
+	Y float64
+}
+
+// Bounding box of a geometry
+type envelope struct {
+	MinX float64 `json:"minX"`
+	MinY float64 `json:"minY"`
+	MaxX float64 `json:"maxX"`
+	MaxY float64 `json:"maxY"`
+}
+
+// Geometry decoded from a GeoPackage geometry blob. Points and line strings use Coordinates,
+// polygons use Rings and multi geometries and collections use Parts. HeaderEnvelope is the
+// envelope stored in the GeoPackage header, when there is one.
+type geometry struct {
+	Type           uint32
+	SrsID          int32
+	Empty          bool
+	HeaderEnvelope *envelope
+	Coordinates    []point
+	Rings          [][]point
+	Parts          []geometry
+}
+
+// Geometry column of a feature layer
+type geometryColumn struct {
+	Name string
+	Type string
+}
+
+// Decode a GeoPackage geometry blob: the GP header followed by WKB
+func decodeGeoPackageBinary(blob []byte) (geometry, error) {
+	if len(blob) < 8 || blob[0] != 'G' || blob[1] != 'P' {
+		return geometry{}, errors.New("not a GeoPackage geometry")
+	}
+	flags := blob[3]
+	if flags&(1<<5) != 0 {
+		return geometry{}, errors.New("extended GeoPackage geometry types are not supported")
+	}
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if flags&1 == 1 {
+		byteOrder = binary.LittleEndian
+	}
+	srsID := int32(byteOrder.Uint32(blob[4:8]))
+	envelopeSizes := []int{0, 32, 48, 48, 64}
+	indicator := int(flags>>1) & 7
+	if indicator >= len(envelopeSizes) {
+		return geometry{}, fmt.Errorf("invalid envelope indicator %d", indicator)
+	}
+	headerSize := 8 + envelopeSizes[indicator]
+	if len(blob) < headerSize {
+		return geometry{}, errors.New("GeoPackage geometry header is truncated")
+	}
+	reader := &wkbReader{data: blob[headerSize:]}
+	geom, err := reader.readGeometry()
+	if err != nil {
+		return geometry{}, err
+	}
+	if env, ok := headerEnvelope(blob); ok {
+		geom.HeaderEnvelope = &env
+	}
+	geom.SrsID = srsID
+	geom.Empty = geom.Empty || flags&(1<<4) != 0
+	return geom, nil
+}
+
+// Read the envelope from the header of a GeoPackage geometry blob without decoding the geometry
+func headerEnvelope(blob []byte) (envelope, bool) {
+	if len(blob) < 40 || blob[0] != 'G' || blob[1] != 'P' {
+		return envelope{}, false
+	}
+	flags := blob[3]
+	if indicator := int(flags>>1) & 7; indicator == 0 || indicator > 4 {
+		return envelope{}, false
+	}
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if flags&1 == 1 {
+		byteOrder = binary.LittleEndian
+	}
+	// the envelope is stored as minx, maxx, miny, maxy, optionally followed by z and m ranges
+	return envelope{
+		MinX: math.Float64frombits(byteOrder.Uint64(blob[8:])),
+		MaxX: math.Float64frombits(byteOrder.Uint64(blob[16:])),
+		MinY: math.Float64frombits(byteOrder.Uint64(blob[24:])),
+		MaxY: math.Float64frombits(byteOrder.Uint64(blob[32:])),
+	}, true
+}
+
+// Whether a point lies within a distance of an envelope
+func (e envelope) near(p point, distance float64) bool {
+	return p.X >= e.MinX-distance && p.X <= e.MaxX+distance && p.Y >= e.MinY-distance && p.Y <= e.MaxY+distance
+}
+
+// Reads WKB, keeping track of the position in the data
+type wkbReader struct {
+	data     []byte
+	position int
+}
+
+func (r *wkbReader) readGeometry() (geometry, error) {
+	if r.position+5 > len(r.data) {
+		return geometry{}, errors.New("WKB is truncated")
+	}
+	var byteOrder binary.ByteOrder = binary.BigEndian
+	if r.data[r.position] == 1 {
+		byteOrder = binary.LittleEndian
+	}
+	wkbType := byteOrder.Uint32(r.data[r.position+1:])
+	r.position += 5
+	dimensions := 2
+	switch wkbType / 1000 {
+	case 1, 2:
+		dimensions = 3
+	case 3:
+		dimensions = 4
+	}
+	geom := geometry{Type: wkbType % 1000}
+	switch geom.Type {
+	case wkbPoint:
+		coordinates, err := r.readPoints(byteOrder, 1, dimensions)
+		if err != nil {
+			return geom, err
+		}
+		if math.IsNaN(coordinates[0].X) && math.IsNaN(coordinates[0].Y) {
+			geom.Empty = true
+		} else {
+			geom.Coordinates = coordinates
+		}
+	case wkbLineString:
+		count, err := r.readCount(byteOrder)
+		if err != nil {
+			return geom, err
+		}
+		if geom.Coordinates, err = r.readPoints(byteOrder, count, dimensions); err != nil {
+			return geom, err
+		}
+		geom.Empty = count == 0
+	case wkbPolygon:
+		count, err := r.readCount(byteOrder)
+		if err != nil {
+			return geom, err
+		}
+		for i := 0; i < count; i++ {
+			points, err := r.readCount(byteOrder)
+			if err != nil {
+				return geom, err
+			}
+			ring, err := r.readPoints(byteOrder, points, dimensions)
+			if err != nil {
+				return geom, err
+			}
+			geom.Rings = append(geom.Rings, ring)
+		}
+		geom.Empty = count == 0
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		count, err := r.readCount(byteOrder)
+		if err != nil {
+			return geom, err
+		}
+		for i := 0; i < count; i++ {
+			part, err := r.readGeometry()
+			if err != nil {
+				return geom, err
+			}
+			geom.Parts = append(geom.Parts, part)
+		}
+		geom.Empty = count == 0
+	default:
+		return geom, fmt.Errorf("unsupported WKB geometry type %d", wkbType)
+	}
+	return geom, nil
+}
+
+func (r *wkbReader) readCount(byteOrder binary.ByteOrder) (int, error) {
+	if r.position+4 > len(r.data) {
+		return 0, errors.New("WKB is truncated")
+	}
+	count := int(byteOrder.Uint32(r.data[r.position:]))
+	r.position += 4
+	return count, nil
+}
+
+func (r *wkbReader) readPoints(byteOrder binary.ByteOrder, count int, dimensions int) ([]point, error) {
+	size := 8 * dimensions
+	if count < 0 || r.position+count*size > len(r.data) {
+		return nil, errors.New("WKB is truncated")
+	}
+	points := make([]point, count)
+	for i := range points {
+		offset := r.position + i*size
+		points[i].X = math.Float64frombits(byteOrder.Uint64(r.data[offset:]))
+		points[i].Y = math.Float64frombits(byteOrder.Uint64(r.data[offset+8:]))
+	}
+	r.position += count * size
+	return points, nil
+}
+
+// Name of the geometry type, like Point or MultiPolygon
+func (g geometry) typeName() string {
+	return geometryTypeNames[g.Type]
+}
+
+// Calculate the bounding box of a geometry
+func (g geometry) envelope() (envelope, bool) {
+	env := envelope{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	found := false
+	g.eachPoint(func(p point) {
+		env.MinX = math.Min(env.MinX, p.X)
+		env.MinY = math.Min(env.MinY, p.Y)
+		env.MaxX = math.Max(env.MaxX, p.X)
+		env.MaxY = math.Max(env.MaxY, p.Y)
+		found = true
+	})
+	return env, found
+}
+
+// Call a function for every coordinate of a geometry
+func (g geometry) eachPoint(f func(p point)) {
+	for _, p := range g.Coordinates {
+		f(p)
+	}
+	for _, ring := range g.Rings {
+		for _, p := range ring {
+			f(p)
+		}
+	}
+	for _, part := range g.Parts {
+		part.eachPoint(f)
+	}
+}
+
+// Calculate the area of a geometry, holes are subtracted from polygons
+func (g geometry) area() float64 {
+	switch g.Type {
+	case wkbPolygon:
+		area := 0.0
+		for i, ring := range g.Rings {
+			if i == 0 {
+				area += math.Abs(ringArea(ring))
+			} else {
+				area -= math.Abs(ringArea(ring))
+			}
+		}
+		return area
+	case wkbMultiPolygon, wkbGeometryCollection:
+		area := 0.0
+		for _, part := range g.Parts {
+			area += part.area()
+		}
+		return area
+	}
+	return 0
+}
+
+// Calculate the length of a geometry, for polygons this is the length of the rings
+func (g geometry) length() float64 {
+	switch g.Type {
+	case wkbLineString:
+		return lineLength(g.Coordinates)
+	case wkbPolygon:
+		length := 0.0
+		for _, ring := range g.Rings {
+			length += lineLength(ring)
+		}
+		return length
+	}
+	length := 0.0
+	for _, part := range g.Parts {
+		length += part.length()
+	}
+	return length
+}
+
+func ringArea(ring []point) float64 {
+	area := 0.0
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		area += (ring[j].X + ring[i].X) * (ring[j].Y - ring[i].Y)
+	}
+	return area / 2
+}
+
+func lineLength(line []point) float64 {
+	length := 0.0
+	for i := 1; i < len(line); i++ {
+		length += math.Hypot(line[i].X-line[i-1].X, line[i].Y-line[i-1].Y)
+	}
+	return length
+}
+
+// Calculate the distance from a point to a geometry, 0 when the point lies inside a polygon
+func (g geometry) distanceTo(p point) float64 {
+	distance := math.Inf(1)
+	switch g.Type {
+	case wkbPoint:
+		for _, c := range g.Coordinates {
+			distance = math.Min(distance, math.Hypot(c.X-p.X, c.Y-p.Y))
+		}
+	case wkbLineString:
+		distance = distanceToLine(g.Coordinates, p)
+	case wkbPolygon:
+		if g.contains(p) {
+			return 0
+		}
+		for _, ring := range g.Rings {
+			distance = math.Min(distance, distanceToLine(ring, p))
+		}
+	default:
+		for _, part := range g.Parts {
+			distance = math.Min(distance, part.distanceTo(p))
+		}
+	}
+	return distance
+}
+
+// Check if a point lies inside a polygon, taking holes into account
+func (g geometry) contains(p point) bool {
+	inside := false
+	for _, ring := range g.Rings {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func distanceToLine(line []point, p point) float64 {
+	distance := math.Inf(1)
+	for i := 1; i < len(line); i++ {
+		distance = math.Min(distance, distanceToSegment(line[i-1], line[i], p))
+	}
+	if len(line) == 1 {
+		distance = math.Hypot(line[0].X-p.X, line[0].Y-p.Y)
+	}
+	return distance
+}
+
+func distanceToSegment(a point, b point, p point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// Convert a geometry to a GeoJSON geometry object
+func (g geometry) geoJSON() map[string]interface{} {
+	coordinates := func(points []point) [][]float64 {
+		result := make([][]float64, len(points))
+		for i, p := range points {
+			result[i] = []float64{p.X, p.Y}
+		}
+		return result
+	}
+	rings := func(rings [][]point) [][][]float64 {
+		result := make([][][]float64, len(rings))
+		for i, ring := range rings {
+			result[i] = coordinates(ring)
+		}
+		return result
+	}
+	object := map[string]interface{}{"type": g.typeName()}
+	switch g.Type {
+	case wkbPoint:
+		if len(g.Coordinates) > 0 {
+			object["coordinates"] = []float64{g.Coordinates[0].X, g.Coordinates[0].Y}
+		} else {
+			object["coordinates"] = []float64{}
+		}
+	case wkbLineString:
+		object["coordinates"] = coordinates(g.Coordinates)
+	case wkbPolygon:
+		object["coordinates"] = rings(g.Rings)
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon:
+		var parts []interface{}
+		for _, part := range g.Parts {
+			parts = append(parts, part.geoJSON()["coordinates"])
+		}
+		object["coordinates"] = parts
+	case wkbGeometryCollection:
+		var parts []interface{}
+		for _, part := range g.Parts {
+			parts = append(parts, part.geoJSON())
+		}
+		object["geometries"] = parts
+	}
+	return object
+}
+
+// Read the geometry column of every feature layer
+func getGeometryColumnPerLayer(geopackage *sql.DB) map[string]geometryColumn {
+	rows, errDb := geopackage.Query("SELECT table_name, column_name, geometry_type_name FROM gpkg_geometry_columns")
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	defer rows.Close()
+	columns := make(map[string]geometryColumn)
+	for rows.Next() {
+		var layer string
+		var column geometryColumn
+		if err := rows.Scan(&layer, &column.Name, &column.Type); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		column.Type = strings.ToUpper(column.Type)
+		columns[layer] = column
+	}
+	return columns
+}
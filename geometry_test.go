@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func Test_decodeGeoPackageBinary(t *testing.T) {
+	blob, _ := hex.DecodeString("4750000140710000010100000000000000804F02410000000040771B41")
+	geom, err := decodeGeoPackageBinary(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.typeName() != "Point" || geom.SrsID != 28992 || geom.Coordinates[0] != (point{150000, 450000}) {
+		t.Errorf("Unexpected geometry: %+v", geom)
+	}
+	// polygon with envelope in the header, big endian WKB
+	blob, _ = hex.DecodeString("4750000200000000000000000000000040240000000000000000000000000000402400000000000000" +
+		"0000000300000001000000050000000000000000000000000000000040240000000000000000000000000000402400000000000040240000000000000000000000000000402400000000000000000000000000000000000000000000")
+	geom, err = decodeGeoPackageBinary(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.typeName() != "Polygon" || len(geom.Rings) != 1 || len(geom.Rings[0]) != 5 {
+		t.Errorf("Unexpected geometry: %+v", geom)
+	}
+	if env, ok := headerEnvelope(blob); !ok || env != (envelope{0, 0, 10, 10}) || !env.near(point{12, 5}, 2) || env.near(point{12, 5}, 1) {
+		t.Errorf("Unexpected header envelope: %+v", env)
+	}
+	if _, err = decodeGeoPackageBinary([]byte("not a geometry")); err == nil {
+		t.Error("Invalid blob should give an error.")
+	}
+}
+
+func Test_distanceTo(t *testing.T) {
+	square := geometry{Type: wkbPolygon, Rings: [][]point{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}}
+	line := geometry{Type: wkbLineString, Coordinates: []point{{0, 0}, {10, 0}}}
+	multi := geometry{Type: wkbMultiPoint, Parts: []geometry{{Type: wkbPoint, Coordinates: []point{{3, 4}}}}}
+	tests := []struct {
+		geom     geometry
+		p        point
+		expected float64
+	}{
+		{square, point{5, 5}, 0},
+		{square, point{15, 5}, 5},
+		{line, point{5, 2}, 2},
+		{multi, point{0, 0}, 5},
+	}
+	for _, test := range tests {
+		if distance := test.geom.distanceTo(test.p); distance != test.expected {
+			t.Errorf("Distance from %v to %s was %f, expected %f.", test.p, test.geom.typeName(), distance, test.expected)
+		}
+	}
+}
+
+func Test_areaAndLength(t *testing.T) {
+	square := geometry{Type: wkbPolygon, Rings: [][]point{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}, {{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}}}
+	if area := square.area(); area != 96 {
+		t.Errorf("Area was %f, expected 96.", area)
+	}
+	line := geometry{Type: wkbMultiLineString, Parts: []geometry{{Type: wkbLineString, Coordinates: []point{{0, 0}, {3, 4}}}}}
+	if length := line.length(); length != 5 {
+		t.Errorf("Length was %f, expected 5.", length)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Attributes with the measures of a geometry. MapServer doesn't calculate these,
+// the layer has to provide them, for example through the DATA statement.
+const geomAreaAttribute = "geom_area"
+const geomLengthAttribute = "geom_length"
+
+// Items of the geometry summary option
+func (o *templateOptions) geometrySummaryItems() []string {
+	var items []string
+	for _, item := range strings.Split(o.geometrySummary, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Add rows with information about the geometry of a feature to a layer
+func addGeometrySummary(layer *templateLayer, items []string) {
+	kind := geometryKind(layer.GeometryType)
+	for _, item := range items {
+		switch item {
+		case "type":
+			layer.Columns = append(layer.Columns, geometrySummaryColumn("geometryType", templateColumn{Static: geometryTypeLabel(layer.GeometryType)}))
+		case "coordinate":
+			tag := `[shpmidx precision="2"], [shpmidy precision="2"]`
+			if kind == "point" {
+				tag = `[shpxy precision="2" xf=", " cs="; "]`
+			}
+			layer.Columns = append(layer.Columns, geometrySummaryColumn("coordinate", templateColumn{Tag: tag}))
+		case "measure":
+			attribute, header, function := geomAreaAttribute, "area", "ST_Area"
+			switch kind {
+			case "line":
+				attribute, header, function = geomLengthAttribute, "length", "ST_Length"
+			case "point":
+				continue
+			case "":
+				log.Printf("Geometry type of layer %s is %s, no measure is added", layer.Name, layer.GeometryType)
+				continue
+			}
+			layer.Columns = append(layer.Columns, geometrySummaryColumn(header, templateColumn{Tag: `[item name="` + attribute + `" precision="2"]`}))
+			log.Printf("The template of layer %s uses the attribute %s, provide it in the MapServer layer, for example with DATA \"SELECT *, %s(%s) AS %s FROM %s\"",
+				layer.Name, attribute, function, layer.GeometryColumn, attribute, layer.Name)
+		}
+	}
+}
+
+// Column of the geometry summary with the English header of a fixed text, translated with the other fixed texts
+func geometrySummaryColumn(text string, column templateColumn) templateColumn {
+	column.Header = uiStrings["en"][text]
+	column.UIText = text
+	return column
+}
+
+// Kind of a declared geometry type: point, line, polygon or empty when unknown
+func geometryKind(geometryType string) string {
+	switch strings.ToUpper(geometryType) {
+	case "POINT", "MULTIPOINT":
+		return "point"
+	case "LINESTRING", "MULTILINESTRING", "CURVE", "MULTICURVE", "COMPOUNDCURVE", "CIRCULARSTRING":
+		return "line"
+	case "POLYGON", "MULTIPOLYGON", "SURFACE", "MULTISURFACE", "CURVEPOLYGON":
+		return "polygon"
+	}
+	return ""
+}
+
+// Readable name of a declared geometry type, like MultiPolygon for MULTIPOLYGON
+func geometryTypeLabel(geometryType string) string {
+	for _, name := range geometryTypeNames {
+		if strings.EqualFold(name, geometryType) {
+			return name
+		}
+	}
+	return geometryType
+}
+
+// Add the measures of a geometry to the attribute values of a feature, as the MapServer layer should provide them
+func addGeometryMeasures(values map[string]string, geom geometry) {
+	if _, ok := values[geomAreaAttribute]; !ok {
+		values[geomAreaAttribute] = strconv.FormatFloat(geom.area(), 'g', 15, 64)
+	}
+	if _, ok := values[geomLengthAttribute]; !ok {
+		values[geomLengthAttribute] = strconv.FormatFloat(geom.length(), 'g', 15, 64)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_addGeometrySummary(t *testing.T) {
+	layer := templateLayer{Name: "percelen", Title: "percelen", GeometryType: "MULTIPOLYGON"}
+	addGeometrySummary(&layer, []string{"type", "coordinate", "measure"})
+	html := generateHTML(layer).String()
+	for _, expected := range []string{
+		"<th>Geometry type</th>", "<td>MultiPolygon</td>",
+		`<td>[shpmidx precision="2"], [shpmidy precision="2"]</td>`,
+		"<th>Area</th>", `<td>[item name="geom_area" precision="2"]</td>`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("HTML doesn't contain %s:\n%s", expected, html)
+		}
+	}
+}
+
+func Test_addGeometrySummary_translated(t *testing.T) {
+	layer := templateLayer{Name: "wegen", Title: "wegen", GeometryType: "LINESTRING"}
+	addGeometrySummary(&layer, []string{"type", "coordinate", "measure"})
+	config := &templateConfig{Translations: map[string]translationConfig{"nl": {UI: map[string]string{"length": "Lengte (m)"}}}}
+	var headers []string
+	for _, column := range translateLayer(layer, "nl", config).Columns {
+		headers = append(headers, column.Header)
+	}
+	if expected := "Geometrietype,Coördinaat,Lengte (m)"; strings.Join(headers, ",") != expected {
+		t.Errorf("Headers were %v, expected %s", headers, expected)
+	}
+}
+
+func Test_geometryKind(t *testing.T) {
+	for geometryType, expected := range map[string]string{"POINT": "point", "MultiLineString": "line", "MULTIPOLYGON": "polygon", "GEOMETRY": ""} {
+		if kind := geometryKind(geometryType); kind != expected {
+			t.Errorf("Kind of %s was %s, expected %s.", geometryType, kind, expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"log"
+	"path"
+	"strings"
+)
+
+// GeoServer GetFeatureInfo templates: header.ftl, content.ftl and footer.ftl in the
+// directory of the feature type, workspaces/<workspace>/<store>/<layer>
+func geoServerFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	dir := path.Join("workspaces", safePathElement(naming.workspace), safePathElement(naming.store), safePathElement(layer.Name))
+	return []generatedFile{
+		{name: languageFileName(path.Join(dir, "header.ftl"), naming), content: []byte(htmlDocumentStart(ftlHeader, layer, freeMarkerText))},
+		{name: languageFileName(path.Join(dir, "content.ftl"), naming), content: generateFreeMarkerContent(layer).Bytes()},
+		{name: languageFileName(path.Join(dir, "footer.ftl"), naming), content: []byte(ftlFooter)},
+	}
+}
+
+// Generate the FreeMarker content template of a layer, with the same columns as the HTML template
+func generateFreeMarkerContent(layer templateLayer) *bytes.Buffer {
+	log.Print("Generate GeoServer content.ftl for layer: " + layer.Name)
+	buf := new(bytes.Buffer)
+	if layer.Locale != "" {
+		buf.WriteString(`<#setting locale="` + freeMarkerLocale(layer.Locale) + `">` + "\n")
+	}
+	buf.WriteString("<#list features as feature>\n")
+	buf.WriteString("\t\t<table class=\"featureInfo\">\n")
+	buf.WriteString("\t\t\t<caption class=\"featureInfo\">" + freeMarkerText(layer.Title) + "</caption>\n")
+	buf.WriteString("\t\t\t<tr>\n")
+	var columns []templateColumn
+	for _, column := range layer.Columns {
+		if column.Tag != "" {
+			log.Printf("Column %s of layer %s uses a MapServer tag and is left out of content.ftl", column.Header, layer.Name)
+			continue
+		}
+		columns = append(columns, column)
+		buf.WriteString("\t\t\t\t<th" + htmlTitleAttribute(column.Description, freeMarkerText) + ">" + freeMarkerText(column.Header) + "</th>\n")
+	}
+	buf.WriteString("\t\t\t</tr>\n\t\t\t<tr>\n")
+	for _, column := range columns {
+		buf.WriteString("\t\t\t\t<td>" + freeMarkerValue(column) + "</td>\n")
+	}
+	buf.WriteString("\t\t\t</tr>\n\t\t</table>\n</#list>\n")
+	return buf
+}
+
+// FreeMarker expression for the value of a column
+func freeMarkerValue(column templateColumn) string {
+	if column.Static != "" {
+		return freeMarkerText(column.Static)
+	}
+	if len(column.Parts) > 0 {
+		return freeMarkerComputed(column)
+	}
+	if len(column.Nested) > 0 {
+		return freeMarkerNested(column)
+	}
+	if column.CodeList != nil {
+		return freeMarkerCodeList(column)
+	}
+	if column.Link != nil {
+		return freeMarkerLink(column)
+	}
+	value := `${((feature["` + freeMarkerString(column.Name) + `"].value)!"")?html}`
+	if format := freeMarkerFormat(column.Format); format != "" {
+		value = `${((feature["` + freeMarkerString(column.Name) + `"].rawValue` + format + `)!"")?html}`
+	}
+	if column.Unit != "" {
+		value += `<#if (feature["` + freeMarkerString(column.Name) + `"].value)?has_content> ` + freeMarkerText(column.Unit) + `</#if>`
+	}
+	return value
+}
+
+// Fixed text like a header or a title in a FreeMarker HTML template, escaped for HTML and with ${ and #{
+// as character references so FreeMarker doesn't read them as interpolations, escaping already turns <# into &lt;#
+func freeMarkerText(text string) string {
+	return strings.NewReplacer("${", "&#36;{", "#{", "&#35;{").Replace(html.EscapeString(text))
+}
+
+// Escape a value for use inside a FreeMarker string literal
+func freeMarkerString(value string) string {
+	return jsonEscape(value)
+}
+
+const ftlHeader = "<html>\n\t<head>\n\t\t<title>GetFeatureInfo output</title>\n\t</head>\n\t<style type=\"text/css\">table.featureInfo, table.featureInfo td, table.featureInfo th { border: 1px solid #ddd; border-collapse: collapse; margin: 0; padding: 0; font-size: 90%; padding: .2em .1em; } table.featureInfo th { padding: .2em .2em; font-weight: bold; background: #eee; } table.featureInfo td { background: #fff; } table.featureInfo tr.odd td { background: #eee; } table.featureInfo caption { text-align: left; font-size: 100%; font-weight: bold; padding: .2em .2em; }</style>\n\t<body>\n"
+const ftlFooter = "\t</body>\n</html>\n<!-- Generated by PDOK ( https://www.pdok.nl/ ) -->"
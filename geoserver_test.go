@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_generateFreeMarkerContent(t *testing.T) {
+	const expectedResult = "<#list features as feature>\n\t\t<table class=\"featureInfo\">\n\t\t\t<caption class=\"featureInfo\">testLayer</caption>\n\t\t\t<tr>\n\t\t\t\t<th>testColumn1</th>\n\t\t\t\t<th>Test kolom 2</th>\n\t\t\t</tr>\n\t\t\t<tr>\n\t\t\t\t<td>${((feature[\"testColumn1\"].value)!\"\")?html}</td>\n\t\t\t\t<td>${((feature[\"testColumn2\"].value)!\"\")?html}</td>\n\t\t\t</tr>\n\t\t</table>\n</#list>\n"
+	layer := newTemplateLayer("testLayer", []string{"testColumn1", "testColumn2", "geom"}, []string{"geom"})
+	layer.Columns[1].Header = "Test kolom 2"
+	result := generateFreeMarkerContent(layer).String()
+	if result != expectedResult {
+		t.Errorf("Result was not OK.\nResult:\n%s.\nExpected:\n%s.", result, expectedResult)
+	}
+}
+
+func Test_generateFreeMarkerContent_escaped(t *testing.T) {
+	layer := newTemplateLayer("putten", []string{"naam"}, nil)
+	layer.Title = "Putten <#if>"
+	layer.Columns[0].Header = "Naam ${x} & #{y}"
+	result := generateFreeMarkerContent(layer).String()
+	for _, expected := range []string{"<caption class=\"featureInfo\">Putten &lt;#if&gt;</caption>", "<th>Naam &#36;{x} &amp; &#35;{y}</th>"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Result doesn't contain %s:\n%s", expected, result)
+		}
+	}
+}
+
+func Test_geoServerFormat(t *testing.T) {
+	layer := newTemplateLayer("putten", []string{"naam"}, nil)
+	files := geoServerFormat(layer, outputNaming{workspace: "afvalwater", store: "afvalwater"})
+	expected := []string{
+		"workspaces/afvalwater/afvalwater/putten/header.ftl",
+		"workspaces/afvalwater/afvalwater/putten/content.ftl",
+		"workspaces/afvalwater/afvalwater/putten/footer.ftl",
+	}
+	for i, file := range files {
+		if file.name != expected[i] {
+			t.Errorf("File name was %s, expected %s.", file.name, expected[i])
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Geometries actually found in a feature layer
+type layerInspection struct {
+	Layer             string         `json:"layer"`
+	GeometryColumn    string         `json:"geometryColumn"`
+	DeclaredType      string         `json:"declaredType"`
+	SrsID             int64          `json:"srsId"`
+	Features          int            `json:"features"`
+	NullGeometries    int            `json:"nullGeometries"`
+	EmptyGeometries   int            `json:"emptyGeometries"`
+	InvalidGeometries int            `json:"invalidGeometries"`
+	GeometryTypes     map[string]int `json:"geometryTypes"`
+	Extent            *envelope      `json:"extent,omitempty"`
+}
+
+// Report the geometry types and extents found per layer
+func inspectCommand(args []string) {
+	startTime := time.Now()
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	gpkgURLParam := flags.String("gpkg-url", "", "URL pointing to a geopackage (https://example.com/geopackage.gpkg)")
+	gpkgPathParam := flags.String("gpkg-path", "", "Path pointing to a geopackage (./geopackage.gpkg)")
+	formatParam := flags.String("format", "text", "Report format: text or json")
+	flags.Parse(args)
+	checkLocationParameters(gpkgURLParam, gpkgPathParam)
+	if *formatParam != "text" && *formatParam != "json" {
+		log.Fatal("Error: unknown format " + *formatParam)
+	}
+	gpkgFile := getGpkgFile(gpkgURLParam, gpkgPathParam)
+	geopackage := openGeopackage(gpkgFile)
+	defer gpkgFile.Close()
+	defer geopackage.Close()
+	inspections := inspectGeopackage(geopackage)
+	writeInspections(os.Stdout, inspections, *formatParam)
+	cleanup(gpkgFile, gpkgURLParam)
+	programFinishedSuccesfully(startTime)
+}
+
+// Inspect the geometries of every feature layer
+func inspectGeopackage(geopackage *sql.DB) []layerInspection {
+	geometryColumns := getGeometryColumnPerLayer(geopackage)
+	metadata := getLayerMetadataFromGeopackage(geopackage)
+	var inspections []layerInspection
+	for _, layer := range getLayersFromGeopackage(geopackage) {
+		column, ok := geometryColumns[layer]
+		if !ok {
+			continue
+		}
+		inspection := inspectLayer(layer, column.Name, geopackage)
+		inspection.DeclaredType = column.Type
+		inspection.SrsID = metadata[layer].SrsID
+		inspections = append(inspections, inspection)
+	}
+	return inspections
+}
+
+// Decode every geometry of a layer
+func inspectLayer(layer string, column string, geopackage *sql.DB) layerInspection {
+	log.Println("Inspecting geometries of layer " + layer)
+	rows, errDb := geopackage.Query("SELECT " + quoteIdentifier(column) + " FROM " + quoteIdentifier(layer))
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	defer rows.Close()
+	inspection := layerInspection{Layer: layer, GeometryColumn: column, GeometryTypes: make(map[string]int)}
+	extent := envelope{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for rows.Next() {
+		var blob []byte
+		if err := rows.Scan(&blob); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		inspection.Features++
+		if blob == nil {
+			inspection.NullGeometries++
+			continue
+		}
+		geom, err := decodeGeoPackageBinary(blob)
+		if err != nil {
+			inspection.InvalidGeometries++
+			continue
+		}
+		if geom.Empty {
+			inspection.EmptyGeometries++
+			continue
+		}
+		inspection.GeometryTypes[geom.typeName()]++
+		if env, ok := geom.envelope(); ok {
+			extent.MinX = math.Min(extent.MinX, env.MinX)
+			extent.MinY = math.Min(extent.MinY, env.MinY)
+			extent.MaxX = math.Max(extent.MaxX, env.MaxX)
+			extent.MaxY = math.Max(extent.MaxY, env.MaxY)
+			inspection.Extent = &extent
+		}
+	}
+	return inspection
+}
+
+// Write the inspections as text or JSON
+func writeInspections(out io.Writer, inspections []layerInspection, format string) {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(inspections); err != nil {
+			log.Fatal("Cannot write inspection: ", err)
+		}
+		return
+	}
+	for _, inspection := range inspections {
+		fmt.Fprintf(out, "Layer %s\n", inspection.Layer)
+		fmt.Fprintf(out, "  geometry column: %s (%s, srs %d)\n", inspection.GeometryColumn, inspection.DeclaredType, inspection.SrsID)
+		fmt.Fprintf(out, "  features: %d (null: %d, empty: %d, invalid: %d)\n",
+			inspection.Features, inspection.NullGeometries, inspection.EmptyGeometries, inspection.InvalidGeometries)
+		var types []string
+		for geometryType := range inspection.GeometryTypes {
+			types = append(types, geometryType)
+		}
+		sort.Strings(types)
+		for _, geometryType := range types {
+			fmt.Fprintf(out, "  %s: %d\n", geometryType, inspection.GeometryTypes[geometryType])
+		}
+		if inspection.Extent != nil {
+			fmt.Fprintf(out, "  extent: %g %g %g %g\n", inspection.Extent.MinX, inspection.Extent.MinY, inspection.Extent.MaxX, inspection.Extent.MaxY)
+		}
+	}
+}
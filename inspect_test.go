@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func Test_inspectGeopackage(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	inspections := inspectGeopackage(geopackage)
+	if len(inspections) != 1 {
+		t.Fatalf("Expected 1 inspection, got %d", len(inspections))
+	}
+	inspection := inspections[0]
+	if inspection.Features != 2 || inspection.GeometryTypes["Point"] != 2 || inspection.DeclaredType != "POINT" {
+		t.Errorf("Unexpected inspection: %+v", inspection)
+	}
+	if inspection.Extent == nil || *inspection.Extent != (envelope{150000, 450000, 150010, 450010}) {
+		t.Errorf("Unexpected extent: %+v", inspection.Extent)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"regexp"
+	"strings"
+)
+
+const jsonSampleSize = 100
+const maxJSONKeys = 20
+
+var jsonKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// How a JSON column is shown, configured in the configuration file
+type jsonConfig struct {
+	Keys   []string `json:"keys,omitempty"`
+	Layout string   `json:"layout,omitempty"`
+}
+
+// Value extracted from a JSON column by the SQL view of a layer, a key of an object or the values of an array
+type jsonExtract struct {
+	Name   string
+	Column string
+	Key    string
+	List   bool
+}
+
+// SQL expression of the extracted value, empty for values that aren't valid JSON
+func (e jsonExtract) expression(layer string) string {
+	column := quoteIdentifier(layer) + "." + quoteIdentifier(e.Column)
+	if e.List {
+		return "CASE WHEN json_valid(" + column + ") THEN (SELECT group_concat(value, ', ') FROM json_each(" + column + ")) END"
+	}
+	path := "$." + e.Key
+	if !jsonKeyRegexp.MatchString(e.Key) {
+		path = `$."` + strings.Replace(e.Key, `"`, `\"`, -1) + `"`
+	}
+	return "CASE WHEN json_valid(" + column + ") THEN json_extract(" + column + ", '" + strings.Replace(path, "'", "''", -1) + "') END"
+}
+
+// Whether SQLite has the JSON functions, they need the sqlite_json1 build tag
+func sqliteHasJSON(geopackage *sql.DB) bool {
+	var valid bool
+	return geopackage.QueryRow("SELECT json_valid('{}')").Scan(&valid) == nil && valid
+}
+
+// Detect JSON objects and arrays in a text column from a sample of the values, with the keys of the objects in the order they are found
+func detectJSONColumn(layer string, column string, geopackage *sql.DB) (bool, bool, []string) {
+	// values that aren't valid JSON become null, so json_type and json_each don't fail on them
+	sample := "SELECT CASE WHEN json_valid(" + quoteIdentifier(column) + ") THEN " + quoteIdentifier(column) + " ELSE 'null' END AS document FROM " +
+		quoteIdentifier(layer) + " WHERE " + quoteIdentifier(column) + " IS NOT NULL AND " + quoteIdentifier(column) + " <> '' LIMIT ?"
+	var count, objects, arrays int
+	err := geopackage.QueryRow("SELECT count(*), coalesce(sum(json_type(document) = 'object'), 0), coalesce(sum(json_type(document) = 'array'), 0) FROM ("+sample+")",
+		jsonSampleSize).Scan(&count, &objects, &arrays)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	if count == 0 || (objects != count && arrays != count) {
+		return false, false, nil
+	}
+	if arrays == count {
+		return true, true, nil
+	}
+	rows, err := geopackage.Query("SELECT key FROM ("+sample+") AS sample, json_each(sample.document)", jsonSampleSize)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	var keys []string
+	found := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		if !found[key] {
+			found[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > maxJSONKeys {
+		log.Printf("Column %s of layer %s has %d JSON keys, only the first %d are shown", column, layer, len(keys), maxJSONKeys)
+		keys = keys[:maxJSONKeys]
+	}
+	return true, false, keys
+}
+
+// Show JSON columns as a nested table with their keys, as separate columns or as a list of array values
+func applyJSONColumns(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	supported := sqliteHasJSON(geopackage)
+	if !supported && options.detectJSON {
+		log.Printf("JSON columns of layer %s are not detected, SQLite is built without JSON support (build with -tags sqlite_json1)", layer.Name)
+	}
+	var columns []templateColumn
+	used := make(map[string]bool)
+	for _, column := range getColumnInfoFromLayer(layer.Name, geopackage) {
+		used[strings.ToLower(column.Name)] = true
+	}
+	for _, column := range layer.ViewColumns {
+		used[strings.ToLower(column.Name)] = true
+	}
+	for _, column := range layer.Columns {
+		var settings *jsonConfig
+		if columnSettings := options.config.column(layer.Name, column.Name); columnSettings != nil {
+			settings = columnSettings.JSON
+		}
+		if !column.hasAttributeValue() || (settings == nil && (!options.detectJSON || !isTextType(column.Type))) ||
+			(settings != nil && settings.Layout == "none") {
+			columns = append(columns, column)
+			continue
+		}
+		if !supported {
+			if settings != nil {
+				log.Fatalf("Error: column %s of layer %s is configured as JSON, but SQLite is built without JSON support (build with -tags sqlite_json1)", column.Name, layer.Name)
+			}
+			columns = append(columns, column)
+			continue
+		}
+		isJSON, isArray, keys := detectJSONColumn(layer.Name, column.Name, geopackage)
+		if settings != nil && settings.Keys != nil {
+			isJSON, isArray, keys = true, false, settings.Keys
+		}
+		if !isJSON {
+			columns = append(columns, column)
+			continue
+		}
+		log.Printf("Column %s of layer %s holds JSON, its values are extracted in the SQL view of the layer", column.Name, layer.Name)
+		if isArray {
+			extract := jsonExtract{Name: column.Name + "_list", Column: column.Name, List: true}
+			if !claimViewColumnName(layer.Name, extract.Name, used) {
+				columns = append(columns, column)
+				continue
+			}
+			layer.ViewColumns = append(layer.ViewColumns, viewColumn{Name: extract.Name, Expression: extract.expression(layer.Name)})
+			columns = append(columns, templateColumn{Name: extract.Name, Header: column.Header, Type: "TEXT", Description: column.Description})
+			continue
+		}
+		var nested []templateColumn
+		for _, key := range keys {
+			extract := jsonExtract{Name: column.Name + "_" + joinNameRegexp.ReplaceAllString(key, "_"), Column: column.Name, Key: key}
+			if !claimViewColumnName(layer.Name, extract.Name, used) {
+				continue
+			}
+			layer.ViewColumns = append(layer.ViewColumns, viewColumn{Name: extract.Name, Expression: extract.expression(layer.Name)})
+			nested = append(nested, templateColumn{Name: extract.Name, Header: key})
+		}
+		if settings != nil && settings.Layout == "columns" {
+			for _, extracted := range nested {
+				extracted.Header = column.Header + " " + extracted.Header
+				columns = append(columns, extracted)
+			}
+			continue
+		}
+		column.Nested = nested
+		columns = append(columns, column)
+	}
+	layer.Columns = columns
+}
+
+// Claim the name of a column of the SQL view, names of the table or of other view columns would hide their values.
+// SQLite compares column names without case.
+func claimViewColumnName(layer string, name string, used map[string]bool) bool {
+	if used[strings.ToLower(name)] {
+		log.Printf("Warning: JSON value %s of layer %s is left out, the table or its SQL view already has a column with that name", name, layer)
+		return false
+	}
+	used[strings.ToLower(name)] = true
+	return true
+}
+
+// MapServer HTML for a nested table with the values of a JSON column
+func mapserverNested(column templateColumn) string {
+	var buf strings.Builder
+	buf.WriteString(`<table class="featureInfo nested">`)
+	for _, nested := range column.Nested {
+		buf.WriteString(`[if name="` + nested.Name + `" oper="neq" value=""]<tr><th>` + mapserverText(nested.Header) + `</th><td>` + mapserverValue(nested, "html") + `</td></tr>[/if]`)
+	}
+	buf.WriteString(`</table>`)
+	return buf.String()
+}
+
+// FreeMarker HTML for a nested table with the values of a JSON column
+func freeMarkerNested(column templateColumn) string {
+	var buf strings.Builder
+	buf.WriteString(`<table class="featureInfo nested">`)
+	for _, nested := range column.Nested {
+		buf.WriteString(`<#if (feature["` + freeMarkerString(nested.Name) + `"].value)?has_content><tr><th>` + freeMarkerText(nested.Header) + `</th><td>` + freeMarkerValue(nested) + `</td></tr></#if>`)
+	}
+	buf.WriteString(`</table>`)
+	return buf.String()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_applyJSONColumns(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	if !sqliteHasJSON(geopackage) {
+		t.Skip("SQLite is built without JSON support, test with -tags sqlite_json1")
+	}
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN adres TEXT",
+		"ALTER TABLE putten ADD COLUMN codes TEXT",
+		`UPDATE putten SET adres = '{"straat": "Dorpsstraat", "huisnummer": 1}', codes = '["A", "B"]' WHERE fid = 1`,
+		`UPDATE putten SET adres = '{"straat": "Kerkweg", "plaats": "Putten"}', codes = '[]' WHERE fid = 2`,
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layer := newTemplateLayer("putten", []string{"naam", "adres", "codes"}, nil)
+	layer.setColumnTypes(getColumnInfoFromLayer("putten", geopackage))
+	applyJSONColumns(&layer, geopackage, &templateOptions{detectJSON: true})
+
+	if len(layer.Columns) != 3 || len(layer.Columns[1].Nested) != 3 || layer.Columns[2].Name != "codes_list" {
+		t.Fatalf("Unexpected columns: %v", layer.Columns)
+	}
+	features := getSampleFeatures(layer, geopackage, 1)
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", features)
+	for _, expected := range []string{
+		`<td><table class="featureInfo nested"><tr><th>straat</th><td>Dorpsstraat</td></tr><tr><th>huisnummer</th><td>1</td></tr></table></td>`,
+		"<td>A, B</td>",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("Rendered template doesn't contain %s:\n%s", expected, rendered)
+		}
+	}
+	files := viewFiles(layer, outputNaming{pattern: defaultFileNamePattern, dataset: "afvalwater"})
+	expected := `CREATE VIEW "putten_view" AS SELECT *, CASE WHEN json_valid("putten"."adres") THEN json_extract("putten"."adres", '$.straat') END AS "adres_straat", ` +
+		`CASE WHEN json_valid("putten"."adres") THEN json_extract("putten"."adres", '$.huisnummer') END AS "adres_huisnummer", ` +
+		`CASE WHEN json_valid("putten"."adres") THEN json_extract("putten"."adres", '$.plaats') END AS "adres_plaats", ` +
+		`CASE WHEN json_valid("putten"."codes") THEN (SELECT group_concat(value, ', ') FROM json_each("putten"."codes")) END AS "codes_list" FROM "putten";`
+	if len(files) != 1 || files[0].name != "putten_view.sql" || !strings.Contains(string(files[0].content), expected) {
+		t.Errorf("Unexpected view files: %v", files)
+	}
+	if _, err := geopackage.Exec(string(files[0].content)); err != nil {
+		t.Errorf("View can't be created: %v", err)
+	}
+}
+
+func Test_applyJSONColumns_columns(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	if !sqliteHasJSON(geopackage) {
+		t.Skip("SQLite is built without JSON support, test with -tags sqlite_json1")
+	}
+	if _, err := geopackage.Exec(`ALTER TABLE putten ADD COLUMN naam_talen TEXT DEFAULT '{"nl": "Put", "en": "Well"}'`); err != nil {
+		t.Fatal(err)
+	}
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {Columns: map[string]columnConfig{
+		"naam_talen": {JSON: &jsonConfig{Keys: []string{"en"}, Layout: "columns"}},
+	}}}}
+	layer := newTemplateLayer("putten", []string{"naam", "naam_talen"}, nil)
+	applyJSONColumns(&layer, geopackage, &templateOptions{config: config})
+	if len(layer.Columns) != 2 || layer.Columns[1].Name != "naam_talen_en" || layer.Columns[1].Header != "naam_talen en" {
+		t.Fatalf("Unexpected columns: %v", layer.Columns)
+	}
+	features := getSampleFeatures(layer, geopackage, 1)
+	if features[0].Values["naam_talen_en"] != "Well" {
+		t.Errorf("Extracted value was %s, expected Well", features[0].Values["naam_talen_en"])
+	}
+}
+
+func Test_applyJSONColumns_nameCollision(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	if !sqliteHasJSON(geopackage) {
+		t.Skip("SQLite is built without JSON support, test with -tags sqlite_json1")
+	}
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN adres TEXT",
+		"ALTER TABLE putten ADD COLUMN Adres_Straat TEXT",
+		`UPDATE putten SET adres = '{"straat": "Dorpsstraat", "huis nummer": 1, "huis_nummer": 2}'`,
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layer := newTemplateLayer("putten", []string{"naam", "adres"}, nil)
+	layer.setColumnTypes(getColumnInfoFromLayer("putten", geopackage))
+	applyJSONColumns(&layer, geopackage, &templateOptions{detectJSON: true})
+	expected := []viewColumn{{Name: "adres_huis_nummer", Expression: `CASE WHEN json_valid("putten"."adres") THEN json_extract("putten"."adres", '$."huis nummer"') END`}}
+	if !reflect.DeepEqual(layer.ViewColumns, expected) || len(layer.Columns[1].Nested) != 1 {
+		t.Errorf("Unexpected view columns: %v", layer.ViewColumns)
+	}
+}
+
+func Test_detectJSONColumn(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	if !sqliteHasJSON(geopackage) {
+		t.Skip("SQLite is built without JSON support, test with -tags sqlite_json1")
+	}
+	tests := []struct {
+		values   []string
+		isJSON   bool
+		isArray  bool
+		expected []string
+	}{
+		{[]string{`{"b": 1, "a": 2}`, ` {"c": null, "a": 3}`}, true, false, []string{"b", "a", "c"}},
+		{[]string{`["A"]`, `[]`}, true, true, nil},
+		{[]string{`{"b": 1}`, `geen json`}, false, false, nil},
+		{[]string{`12`, `"tekst"`}, false, false, nil},
+	}
+	for i, test := range tests {
+		column := fmt.Sprintf("json%d", i)
+		if _, err := geopackage.Exec("ALTER TABLE putten ADD COLUMN " + column + " TEXT"); err != nil {
+			t.Fatal(err)
+		}
+		for fid, value := range test.values {
+			if _, err := geopackage.Exec("UPDATE putten SET "+column+" = ? WHERE fid = ?", value, fid+1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		isJSON, isArray, keys := detectJSONColumn("putten", column, geopackage)
+		if isJSON != test.isJSON || isArray != test.isArray || !reflect.DeepEqual(keys, test.expected) {
+			t.Errorf("Detected %v, %v, %v for %v", isJSON, isArray, keys, test.values)
+		}
+	}
+}
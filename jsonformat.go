@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// MapServer JSON template: an object with the layer name and the attributes of every feature
+func jsonFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	content := generateJSON(layer, false)
+	validateJSONTemplate(layer, content)
+	return []generatedFile{{name: outputFileName(naming.pattern, naming.dataset, layer.Name, "json"), content: []byte(content)}}
+}
+
+// MapServer GeoJSON template: a FeatureCollection, with the geometry when the layer has one
+func geoJSONFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	content := generateJSON(layer, true)
+	validateJSONTemplate(layer, content)
+	return []generatedFile{{name: outputFileName(naming.pattern, naming.dataset, layer.Name, "geojson"), content: []byte(content)}}
+}
+
+// Generate a JSON or GeoJSON template for a layer
+func generateJSON(layer templateLayer, geoJSON bool) string {
+	log.Print("Generate JSON for layer: " + layer.Name)
+	var buf strings.Builder
+	buf.WriteString(templateMagic)
+	buf.WriteString(`[resultset layer="` + layer.Name + `"]` + "\n")
+	buf.WriteString("{\n")
+	if geoJSON {
+		buf.WriteString("\t\"type\": \"FeatureCollection\",\n")
+	} else {
+		buf.WriteString("\t\"layer\": \"" + jsonEscape(layer.Title) + "\",\n")
+	}
+	buf.WriteString("\t\"features\": [\n")
+	buf.WriteString("[feature trimlast=\",\"]\n")
+	indent := "\t\t\t"
+	if geoJSON {
+		buf.WriteString("\t\t{\n\t\t\t\"type\": \"Feature\",\n")
+		buf.WriteString("\t\t\t\"geometry\": " + geoJSONGeometryTemplate(layer.GeometryType) + ",\n")
+		buf.WriteString("\t\t\t\"properties\": {\n")
+		indent = "\t\t\t\t"
+	} else {
+		buf.WriteString("\t\t{\n")
+	}
+	for i, column := range layer.Columns {
+		key := column.Name
+		if key == "" {
+			key = column.Header
+		}
+		buf.WriteString(indent + `"` + jsonEscape(key) + `": ` + jsonValue(column))
+		if i < len(layer.Columns)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	if geoJSON {
+		buf.WriteString("\t\t\t}\n")
+	}
+	buf.WriteString("\t\t},\n")
+	buf.WriteString("[/feature]\n")
+	buf.WriteString("\t]\n}\n")
+	buf.WriteString("[/resultset]\n")
+	return buf.String()
+}
+
+// Values that are JSON numbers, and values of numeric columns that aren't. SQLite doesn't enforce column types,
+// so a numeric column can hold text like n.v.t. that has to be written as a string.
+const jsonNumberPattern = `^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`
+const jsonNotNumberPattern = `[^-.0-9]|.-|\..*\.|^-?\.|\.$|^-$|^-?0[0-9]`
+
+// Values of boolean columns other than 0 and 1
+const jsonNotBooleanPattern = `[^01]|..`
+
+// JSON value of a column: numbers unquoted, booleans as true or false, codes as their label and everything else as a string.
+// Values of numeric and boolean columns that aren't numbers or booleans are written as strings.
+func jsonValue(column templateColumn) string {
+	switch {
+	case column.Static != "":
+		return `"` + escapeStatic(column.Static, "json") + `"`
+	case column.Tag != "":
+		if strings.HasPrefix(column.Tag, "[item ") && !strings.Contains(column.Tag, "format=") {
+			return strings.Replace(column.Tag, "[item ", `[item nullformat="null" `, 1)
+		}
+		return `"` + column.Tag + `"`
+	case column.CodeList != nil:
+		return `[if name="` + column.Name + `" oper="isnull"]null[/if][if name="` + column.Name + `" oper="isset"]"` + mapserverCodeList(column, "json") + `"[/if]`
+	case isBooleanType(column.Type):
+		return `[if name="` + column.Name + `" oper="isnull"]null[/if][if name="` + column.Name + `" oper="eq" value="1"]true[/if][if name="` + column.Name + `" oper="eq" value="0"]false[/if]` +
+			jsonStringFallback(column.Name, jsonNotBooleanPattern)
+	case isNumericType(column.Type):
+		return `[item name="` + column.Name + `" pattern="` + jsonNumberPattern + `" nullformat="null"]` + jsonStringFallback(column.Name, jsonNotNumberPattern)
+	}
+	return `"` + mapserverValue(column.unformatted(), "json") + `"`
+}
+
+// [item] writing the value of a column as a JSON string when it matches a pattern
+func jsonStringFallback(name string, pattern string) string {
+	return `[item name="` + name + `" pattern="` + pattern + `" format='"$value"' escape="json"]`
+}
+
+// GeoJSON geometry of a feature through [shpxy], MapServer only knows parts so holes in multi polygons can't be told apart
+func geoJSONGeometryTemplate(geometryType string) string {
+	coordinate := `xh="[" xf="," yf="]" precision="6"`
+	switch strings.ToUpper(geometryType) {
+	case "POINT":
+		return `{"type": "Point", "coordinates": [shpxy ` + coordinate + `]}`
+	case "MULTIPOINT", "LINESTRING":
+		return `{"type": "` + geometryTypeLabel(geometryType) + `", "coordinates": [[shpxy ` + coordinate + ` cs=","]]}`
+	case "MULTILINESTRING", "POLYGON":
+		return `{"type": "` + geometryTypeLabel(geometryType) + `", "coordinates": [[shpxy ph="[" pf="]" ps="," ` + coordinate + ` cs=","]]}`
+	case "MULTIPOLYGON":
+		log.Print("MapServer can't tell holes from polygons, every ring of a MultiPolygon becomes a polygon in the GeoJSON template")
+		return `{"type": "MultiPolygon", "coordinates": [[shpxy ph="[[" pf="]]" ps="," ` + coordinate + ` cs=","]]}`
+	}
+	return "null"
+}
+
+func isNumericType(columnType string) bool {
+	columnType = strings.ToUpper(columnType)
+	for _, numeric := range []string{"INT", "REAL", "FLOA", "DOUB", "NUMERIC", "DECIMAL"} {
+		if strings.Contains(columnType, numeric) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBooleanType(columnType string) bool {
+	return strings.EqualFold(columnType, "BOOLEAN")
+}
+
+// Render a JSON template with test features and make sure the result parses
+func validateJSONTemplate(layer templateLayer, template string) {
+	for count := 1; count <= 2; count++ {
+		var features []templateFeature
+		for i := 0; i < count; i++ {
+			features = append(features, validationFeature(layer, i))
+		}
+		rendered := renderMapserverTemplate(template, layer.Name, features)
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(rendered), &parsed); err != nil {
+			log.Fatalf("Generated JSON template for layer %s doesn't render valid JSON: %v\n%s", layer.Name, err, rendered)
+		}
+	}
+}
+
+// Feature with values for every column that are hard to get right in JSON, the second feature has NULL values
+func validationFeature(layer templateLayer, index int) templateFeature {
+	feature := templateFeature{Values: make(map[string]string)}
+	columns := append([]templateColumn{}, layer.Columns...)
+	for _, column := range layer.Columns {
+		for _, part := range column.Parts {
+			columns = append(columns, *part.Column)
+		}
+	}
+	for _, column := range columns {
+		value := ""
+		if index == 0 {
+			value = "waarde \"met\" \\ tekens\n"
+			if (isNumericType(column.Type) || isBooleanType(column.Type)) && column.CodeList == nil {
+				value = "1"
+			}
+		}
+		feature.Values[column.Name] = value
+	}
+	addGeometryMeasures(feature.Values, geometry{})
+	geom := validationGeometry(layer.GeometryType)
+	feature.Geometry = &geom
+	return feature
+}
+
+// Geometry of the declared type, used to validate generated templates
+func validationGeometry(geometryType string) geometry {
+	ring := []point{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+	line := geometry{Type: wkbLineString, Coordinates: ring[:3]}
+	polygon := geometry{Type: wkbPolygon, Rings: [][]point{ring, ring}}
+	switch strings.ToUpper(geometryType) {
+	case "MULTIPOINT":
+		return geometry{Type: wkbMultiPoint, Parts: []geometry{{Type: wkbPoint, Coordinates: ring[:1]}, {Type: wkbPoint, Coordinates: ring[1:2]}}}
+	case "LINESTRING":
+		return line
+	case "MULTILINESTRING":
+		return geometry{Type: wkbMultiLineString, Parts: []geometry{line, line}}
+	case "POLYGON":
+		return polygon
+	case "MULTIPOLYGON":
+		return geometry{Type: wkbMultiPolygon, Parts: []geometry{polygon, polygon}}
+	}
+	return geometry{Type: wkbPoint, Coordinates: ring[:1]}
+}
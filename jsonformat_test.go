@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testJSONLayer() templateLayer {
+	layer := newTemplateLayer("putten", []string{"fid", "geom", "naam", "diepte", "actief"}, []string{"geom"})
+	layer.setColumnTypes([]columnInfo{{"fid", "INTEGER"}, {"geom", "POINT"}, {"naam", "TEXT"}, {"diepte", "REAL"}, {"actief", "BOOLEAN"}})
+	layer.GeometryType = "POINT"
+	return layer
+}
+
+func Test_generateJSON(t *testing.T) {
+	features := []templateFeature{
+		{Values: map[string]string{"fid": "1", "naam": "Put \"1\"", "diepte": "2.5", "actief": "1"}},
+		{Values: map[string]string{"fid": "2", "naam": "", "diepte": "", "actief": "0"}},
+	}
+	rendered := renderMapserverTemplate(generateJSON(testJSONLayer(), false), "putten", features)
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rendered)
+	}
+	expected := map[string]interface{}{
+		"layer": "putten",
+		"features": []interface{}{
+			map[string]interface{}{"fid": 1.0, "naam": "Put \"1\"", "diepte": 2.5, "actief": true},
+			map[string]interface{}{"fid": 2.0, "naam": "", "diepte": nil, "actief": false},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result was not OK.\nResult:\n%v\nExpected:\n%v", result, expected)
+	}
+}
+
+func Test_generateJSON_invalidValues(t *testing.T) {
+	features := []templateFeature{
+		{Values: map[string]string{"fid": "n.v.t.", "naam": "Put 1", "diepte": "", "actief": "ja"}},
+		{Values: map[string]string{"fid": "007", "naam": "Put 2", "diepte": "-0.5", "actief": "1"}},
+		{Values: map[string]string{"fid": "1.2.3", "naam": "Put 3", "diepte": "1e3", "actief": "0"}},
+	}
+	rendered := renderMapserverTemplate(generateJSON(testJSONLayer(), false), "putten", features)
+	var result struct {
+		Features []map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(rendered), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, rendered)
+	}
+	expected := []map[string]interface{}{
+		{"fid": "n.v.t.", "naam": "Put 1", "diepte": nil, "actief": "ja"},
+		{"fid": "007", "naam": "Put 2", "diepte": -0.5, "actief": true},
+		{"fid": "1.2.3", "naam": "Put 3", "diepte": "1e3", "actief": false},
+	}
+	if !reflect.DeepEqual(result.Features, expected) {
+		t.Errorf("Result was not OK.\nResult:\n%v\nExpected:\n%v", result.Features, expected)
+	}
+}
+
+func Test_generateGeoJSON(t *testing.T) {
+	features := []templateFeature{{
+		Values:   map[string]string{"fid": "1", "naam": "Put 1", "diepte": "2.5", "actief": "1"},
+		Geometry: &geometry{Type: wkbPoint, Coordinates: []point{{150000, 450000}}},
+	}}
+	rendered := renderMapserverTemplate(generateJSON(testJSONLayer(), true), "putten", features)
+	var result struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates []float64
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(rendered), &result); err != nil {
+		t.Fatalf("Invalid GeoJSON: %v\n%s", err, rendered)
+	}
+	if result.Type != "FeatureCollection" || len(result.Features) != 1 || !reflect.DeepEqual(result.Features[0].Geometry.Coordinates, []float64{150000, 450000}) {
+		t.Errorf("Unexpected GeoJSON:\n%s", rendered)
+	}
+}
+
+func Test_validateJSONTemplate(t *testing.T) {
+	for _, geometryType := range []string{"POINT", "MULTIPOINT", "LINESTRING", "MULTILINESTRING", "POLYGON", "MULTIPOLYGON", "GEOMETRY"} {
+		layer := testJSONLayer()
+		layer.GeometryType = geometryType
+		addGeometrySummary(&layer, []string{"type", "coordinate", "measure"})
+		validateJSONTemplate(layer, generateJSON(layer, true))
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"database/sql"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const linkSampleSize = 100
+const defaultImageWidth = 200
+
+// How a column is shown as a link or an image. Href is a pattern where {value} inserts the value of the column
+// as it is and {column} the URL encoded value of a column, Base is put in front of it. A link that starts with
+// {value} only gets values with an http or https scheme.
+type linkFormat struct {
+	Type   string `json:"type"`
+	Href   string `json:"href,omitempty"`
+	Base   string `json:"base,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Target string `json:"target,omitempty"`
+	Rel    string `json:"rel,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+var linkPlaceholderRegexp = regexp.MustCompile(`\{([^{}]+)\}`)
+var urlValueRegexp = regexp.MustCompile(`^https?://[^\s"'<>]+$`)
+var emailValueRegexp = regexp.MustCompile(`^[^\s@"'<>]+@[^\s@"'<>]+\.[^\s@"'<>]+$`)
+var imageValueRegexp = regexp.MustCompile(`(?i)\.(jpe?g|png|gif|webp|svg)$`)
+
+// Set the links of the columns of a layer, from the config or detected from a sample of the values
+func applyLinks(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	for i, column := range layer.Columns {
+		if column.Name == "" || column.Static != "" || column.Tag != "" {
+			continue
+		}
+		var link *linkFormat
+		if settings := options.config.column(layer.Name, column.Name); settings != nil && settings.Link != nil {
+			link = settings.Link
+		} else if options.detectLinks && geopackage != nil && isTextType(column.Type) {
+			link = detectLink(layer.Name, column.Name, geopackage)
+		}
+		if link == nil || link.Type == "none" {
+			continue
+		}
+		checkLink(*layer, column.Name, *link)
+		layer.Columns[i].Link = link
+	}
+}
+
+// Check the type and the placeholders of a link
+func checkLink(layer templateLayer, column string, link linkFormat) {
+	if link.Type != "link" && link.Type != "email" && link.Type != "image" {
+		log.Fatalf("Error: unknown link type %s for column %s of layer %s, use link, email, image or none", link.Type, column, layer.Name)
+	}
+	for _, match := range linkPlaceholderRegexp.FindAllStringSubmatch(link.Base+link.Href, -1) {
+		if match[1] != "value" && !layer.hasColumn(match[1]) {
+			log.Fatalf("Error: link of column %s of layer %s uses unknown column {%s}", column, layer.Name, match[1])
+		}
+	}
+}
+
+// Detect URLs, email addresses and image URLs from a sample of the values of a column
+func detectLink(layer string, column string, geopackage *sql.DB) *linkFormat {
+	rows, err := geopackage.Query("SELECT "+quoteIdentifier(column)+" FROM "+quoteIdentifier(layer)+
+		" WHERE "+quoteIdentifier(column)+" IS NOT NULL AND "+quoteIdentifier(column)+" <> '' LIMIT ?", linkSampleSize)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	urls, emails, images, relativeImages, count := 0, 0, 0, 0, 0
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		count++
+		value = strings.TrimSpace(value)
+		switch {
+		case urlValueRegexp.MatchString(value) && imageValueRegexp.MatchString(value):
+			images++
+		case urlValueRegexp.MatchString(value):
+			urls++
+		case emailValueRegexp.MatchString(value):
+			emails++
+		case imageValueRegexp.MatchString(value) && !strings.ContainsAny(value, ":\"'<>"):
+			relativeImages++
+		}
+	}
+	switch {
+	case count == 0:
+		return nil
+	case images == count:
+		log.Printf("Column %s of layer %s holds image URLs and is shown as an image", column, layer)
+		return &linkFormat{Type: "image"}
+	case urls+images == count:
+		log.Printf("Column %s of layer %s holds URLs and is shown as a link", column, layer)
+		return &linkFormat{Type: "link"}
+	case emails == count:
+		log.Printf("Column %s of layer %s holds email addresses and is shown as a mailto link", column, layer)
+		return &linkFormat{Type: "email"}
+	case relativeImages == count:
+		log.Printf("Column %s of layer %s holds image paths, configure a link of type image with a base URL to show them", column, layer)
+	}
+	return nil
+}
+
+// Whether a column type holds text, columns without a declared type are treated as text
+func isTextType(columnType string) bool {
+	columnType = strings.ToUpper(columnType)
+	return !isNumericType(columnType) && !isBooleanType(columnType) && columnType != "DATE" && columnType != "DATETIME" && columnType != "BLOB"
+}
+
+// Whether a layer has a column with an attribute name
+func (l templateLayer) hasColumn(name string) bool {
+	for _, column := range l.Columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Link pattern with the base URL, mailto for email links
+func (l linkFormat) url() string {
+	href := l.Href
+	if href == "" {
+		href = "{value}"
+	}
+	if l.Type == "email" && l.Href == "" {
+		return "mailto:" + href
+	}
+	return l.Base + href
+}
+
+// How a value is inserted in a link: as it is, URL encoded, or as the start of the URL,
+// which is only inserted with an http or https scheme so values can't make javascript: links
+const (
+	linkValueRaw = iota
+	linkValueEncoded
+	linkValueURL
+)
+
+// Scheme a value at the start of a link needs
+const linkSchemePattern = "^https?://"
+
+// Fill the placeholders of a link with the fixed parts and values rendered by the template language
+func (l linkFormat) render(column string, value func(name string, insert int) string) string {
+	pattern := l.url()
+	var buf strings.Builder
+	last := 0
+	for _, match := range linkPlaceholderRegexp.FindAllStringSubmatchIndex(pattern, -1) {
+		buf.WriteString(html.EscapeString(pattern[last:match[0]]))
+		name := pattern[match[2]:match[3]]
+		switch {
+		case name == "value" && match[0] == 0:
+			buf.WriteString(value(column, linkValueURL))
+		case name == "value":
+			buf.WriteString(value(column, linkValueRaw))
+		default:
+			buf.WriteString(value(name, linkValueEncoded))
+		}
+		last = match[1]
+	}
+	buf.WriteString(html.EscapeString(pattern[last:]))
+	return buf.String()
+}
+
+// HTML attributes of a link or an image, after the href or src, alt is already escaped
+func (l linkFormat) attributes(alt string) string {
+	if l.Type == "image" {
+		attributes := ` alt="` + alt + `"`
+		if l.Width == 0 && l.Height == 0 {
+			l.Width = defaultImageWidth
+		}
+		if l.Width > 0 {
+			attributes += ` width="` + strconv.Itoa(l.Width) + `"`
+		}
+		if l.Height > 0 {
+			attributes += ` height="` + strconv.Itoa(l.Height) + `"`
+		}
+		return attributes
+	}
+	if l.Type == "email" {
+		return ""
+	}
+	target, rel := l.Target, l.Rel
+	if target == "" {
+		target = "_blank"
+	}
+	if rel == "" && target == "_blank" {
+		rel = "noopener noreferrer"
+	}
+	attributes := ` target="` + html.EscapeString(target) + `"`
+	if rel != "" {
+		attributes += ` rel="` + html.EscapeString(rel) + `"`
+	}
+	return attributes
+}
+
+// HTML for a link or an image, around the text shown for the value, escape escapes the fixed texts for the template language
+func (l linkFormat) html(href string, text string, alt string, escape func(text string) string) string {
+	if l.Type == "image" {
+		return `<img src="` + href + `"` + l.attributes(escape(alt)) + `>`
+	}
+	if l.Text != "" {
+		text = escape(l.Text)
+	}
+	return `<a href="` + href + `"` + l.attributes(escape(alt)) + `>` + text + `</a>`
+}
+
+// MapServer HTML for a link column, left out when the value is empty
+func mapserverLink(column templateColumn) string {
+	href := column.Link.render(column.Name, func(name string, insert int) string {
+		switch insert {
+		case linkValueEncoded:
+			return `[item name="` + name + `" escape="url"]`
+		case linkValueURL:
+			return `[item name="` + name + `" pattern="` + linkSchemePattern + `" escape="html"]`
+		}
+		return `[item name="` + name + `" escape="html"]`
+	})
+	text := mapserverValue(column.withoutLink(), "html")
+	return `[if name="` + column.Name + `" oper="isset"]` + column.Link.html(href, text, column.Header, mapserverText) + `[/if]`
+}
+
+// FreeMarker HTML for a link column, left out when the value is empty
+func freeMarkerLink(column templateColumn) string {
+	href := column.Link.render(column.Name, func(name string, insert int) string {
+		value := `((feature["` + freeMarkerString(name) + `"].value)!"")`
+		switch insert {
+		case linkValueEncoded:
+			return `${` + value + `?url?html}`
+		case linkValueURL:
+			return `<#if ` + value + `?matches("` + linkSchemePattern + `.*")>${` + value + `?html}</#if>`
+		}
+		return `${` + value + `?html}`
+	})
+	text := freeMarkerValue(column.withoutLink())
+	return `<#if (feature["` + freeMarkerString(column.Name) + `"].value)?has_content>` + column.Link.html(href, text, column.Header, freeMarkerText) + `</#if>`
+}
+
+// Column shown as text instead of a link
+func (c templateColumn) withoutLink() templateColumn {
+	c.Link = nil
+	return c
+}
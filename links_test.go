@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_detectLink(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN website TEXT",
+		"ALTER TABLE putten ADD COLUMN email TEXT",
+		"ALTER TABLE putten ADD COLUMN foto TEXT",
+		"UPDATE putten SET website = 'https://example.com/put/' || fid, email = 'beheer' || fid || '@example.com', foto = 'https://example.com/' || fid || '.jpg'",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]string{"naam": "", "website": "link", "email": "email", "foto": "image"}
+	for column, linkType := range expected {
+		link := detectLink("putten", column, geopackage)
+		if (link == nil && linkType != "") || (link != nil && link.Type != linkType) {
+			t.Errorf("Link of column %s was %v, expected %s", column, link, linkType)
+		}
+	}
+}
+
+func testLinkLayer() templateLayer {
+	layer := newTemplateLayer("putten", []string{"identificatie", "website", "email", "foto"}, nil)
+	layer.Columns[0].Link = &linkFormat{Type: "link", Href: "https://viewer.example/?id={identificatie}", Text: "Bekijk & bewerk"}
+	layer.Columns[1].Link = &linkFormat{Type: "link"}
+	layer.Columns[2].Link = &linkFormat{Type: "email"}
+	layer.Columns[3].Link = &linkFormat{Type: "image", Base: "https://fotos.example/", Height: 100}
+	return layer
+}
+
+func Test_mapserverLink(t *testing.T) {
+	features := []templateFeature{{Values: map[string]string{
+		"identificatie": "PUT 1&2",
+		"website":       `https://example.com/?a=1&b="2"`,
+		"email":         "beheer@example.com",
+		"foto":          "",
+	}}}
+	rendered := renderMapserverTemplate(generateHTML(testLinkLayer()).String(), "putten", features)
+	expected := []string{
+		`<td><a href="https://viewer.example/?id=PUT+1%262" target="_blank" rel="noopener noreferrer">Bekijk &amp; bewerk</a></td>`,
+		`<td><a href="https://example.com/?a=1&amp;b=&#34;2&#34;" target="_blank" rel="noopener noreferrer">https://example.com/?a=1&amp;b=&#34;2&#34;</a></td>`,
+		`<td><a href="mailto:beheer@example.com">beheer@example.com</a></td>`,
+		"<td></td>",
+	}
+	for _, value := range expected {
+		if !strings.Contains(rendered, value) {
+			t.Errorf("Rendered template doesn't contain %s:\n%s", value, rendered)
+		}
+	}
+	features[0].Values["website"] = "javascript:alert(1)"
+	rendered = renderMapserverTemplate(generateHTML(testLinkLayer()).String(), "putten", features)
+	if link := `<td><a href="" target="_blank" rel="noopener noreferrer">javascript:alert(1)</a></td>`; !strings.Contains(rendered, link) {
+		t.Errorf("Rendered template doesn't contain %s:\n%s", link, rendered)
+	}
+	features[0].Values["foto"] = "2020/put 1.jpg"
+	rendered = renderMapserverTemplate(generateHTML(testLinkLayer()).String(), "putten", features)
+	if image := `<td><img src="https://fotos.example/2020/put 1.jpg" alt="foto" height="100"></td>`; !strings.Contains(rendered, image) {
+		t.Errorf("Rendered template doesn't contain %s:\n%s", image, rendered)
+	}
+}
+
+func Test_freeMarkerLink(t *testing.T) {
+	column := testLinkLayer().Columns[0]
+	expected := `<#if (feature["identificatie"].value)?has_content><a href="https://viewer.example/?id=${((feature["identificatie"].value)!"")?url?html}" target="_blank" rel="noopener noreferrer">Bekijk &amp; bewerk</a></#if>`
+	if result := freeMarkerValue(column); result != expected {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expected)
+	}
+}
+
+func Test_freeMarkerLink_scheme(t *testing.T) {
+	column := testLinkLayer().Columns[1]
+	expected := `<a href="<#if ((feature["website"].value)!"")?matches("^https?://.*")>${((feature["website"].value)!"")?html}</#if>"`
+	if result := freeMarkerValue(column); !strings.Contains(result, expected) {
+		t.Errorf("Result was not OK.\nResult:\n%s\nExpected:\n%s", result, expected)
+	}
+}
+
+func Test_applyLinks_personalData(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN email TEXT",
+		"UPDATE putten SET email = 'beheer' || fid || '@example.com'",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	options := &templateOptions{detectLinks: true, detectPII: true}
+	layer := buildTemplateLayer("putten", geopackage, []string{"geom"}, options)
+	for _, column := range layer.Columns {
+		if column.Name == "email" {
+			t.Error("Email column should be left out as personal data before links are detected")
+		}
+	}
+	options.config = &templateConfig{AllowPersonalData: []string{"putten.email"}}
+	layer = buildTemplateLayer("putten", geopackage, []string{"geom"}, options)
+	if !strings.Contains(mapserverValue(layer.Columns[len(layer.Columns)-1], "html"), `<a href="mailto:`) {
+		t.Errorf("Allowed email column should be a mailto link: %v", layer.Columns)
+	}
+}
@@ -17,23 +17,88 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Commands next to the default generation of templates
+var commands = map[string]func(args []string){
+	"schema-diff":    schemaDiffCommand,
+	"preview":        previewCommand,
+	"preview-server": previewServerCommand,
+	"inspect":        inspectCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
 	startTime := time.Now()
 	gpkgURLParam := flag.String("gpkg-url", "", "URL pointing to a geopackage (https://example.com/geopackage.gpkg)")
 	gpkgPathParam := flag.String("gpkg-path", "", "Path pointing to a geopackage (./geopackage.gpkg)")
+	outputParam := flag.String("output", defaultOutput, "Output directory, .zip or .tar.gz archive, or - for a tar.gz stream on stdout")
+	fileNamePatternParam := flag.String("filename-pattern", defaultFileNamePattern, "Pattern for generated file names, supports {dataset}, {layer}, {format} and {lang} (with -lang)")
+	pruneParam := flag.Bool("prune", false, "Delete files from earlier runs for layers that are no longer in the Geopackage (directory output only)")
+	checkParam := flag.Bool("check", false, "Compare the generated files with the output directory instead of writing them, exits with 1 on differences")
+	formatParam := flag.String("format", defaultFormats, "Comma separated output formats: "+strings.Join(formatNames(), ", "))
+	workspaceParam := flag.String("geoserver-workspace", "", "GeoServer workspace for the geoserver format (default the name of the dataset)")
+	storeParam := flag.String("geoserver-store", "", "GeoServer store for the geoserver format (default the name of the dataset)")
+	reportParam := flag.String("report", "", "JSON file to write the run report to, with the column profiles of -profile and the columns with personal data")
+	options := registerTemplateFlags(flag.CommandLine)
 	checkParameters(gpkgURLParam, gpkgPathParam)
+	if *checkParam {
+		checkCheckOutput(*outputParam)
+	}
+	formats := parseFormats(*formatParam)
+	options.check()
 	gpkgFile := getGpkgFile(gpkgURLParam, gpkgPathParam)
 	geopackage := openGeopackage(gpkgFile)
 	defer gpkgFile.Close()
 	defer geopackage.Close()
 	geomColumns := getGeometryColumnsFromGeopackage(geopackage)
 	layers := getLayersFromGeopackage(geopackage)
+	dataset := datasetName(gpkgURLParam, gpkgPathParam)
+	naming := outputNaming{pattern: *fileNamePatternParam, dataset: dataset, workspace: *workspaceParam, store: *storeParam}
+	if naming.workspace == "" {
+		naming.workspace = dataset
+	}
+	if naming.store == "" {
+		naming.store = dataset
+	}
+	// Build every layer before the output is opened, so a failing layer leaves the output and its lock untouched
+	sink := newMemorySink()
+	var reports []layerReport
+	languages := options.languages()
+	if languages == nil {
+		languages = []string{""}
+	}
 	for _, layer := range layers {
-		columns := getPropertiesFromLayer(layer, geopackage)
-		htmlBuffer := generateHTMLForLayer(layer, columns, geomColumns)
-		writeHTMLfile(layer, htmlBuffer)
+		model := buildTemplateLayer(layer, geopackage, geomColumns, options)
+		reports = append(reports, model.report())
+		for _, language := range languages {
+			languageModel, languageNames := translateLayer(model, language, options.config), naming
+			if len(languages) > 1 || strings.Contains(naming.pattern, "{lang}") {
+				languageNames = languageNaming(naming, language)
+			}
+			for _, format := range formats {
+				for _, file := range templateFormats[format](languageModel, languageNames) {
+					sink.write(file.name, file.content)
+				}
+			}
+		}
+		for _, file := range viewFiles(model, naming) {
+			sink.write(file.name, file.content)
+		}
+	}
+	if !*checkParam {
+		sink.copyTo(openOutputSink(*outputParam, *pruneParam))
+	}
+	if *reportParam != "" {
+		writeReport(*reportParam, reports)
 	}
 	cleanup(gpkgFile, gpkgURLParam)
+	if *checkParam && checkOutputDir(os.Stdout, *outputParam, sink.files) {
+		log.Fatal("Generated files differ from " + *outputParam)
+	}
 	programFinishedSuccesfully(startTime)
 }
 
@@ -76,6 +141,15 @@ func getGpkgFile(gpkgURLParam *string, gpkgPathParam *string) *os.File {
 	return gpkgFile
 }
 
+// Get the Geopackage parameters for a location that is either an URL or a path
+func gpkgLocationParams(location string) (*string, *string) {
+	empty := ""
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return &location, &empty
+	}
+	return &empty, &location
+}
+
 // Download a Geopackage and store it in a file
 func downloadGeopackage(gpkgFile *os.File, url string) {
 	log.Printf("Starting download for: %s", url)
@@ -163,15 +237,25 @@ func getGeometryColumnsFromGeopackage(geopackage *sql.DB) []string {
 
 // Generate HTML for layer
 func generateHTMLForLayer(layer string, columns []string, geomColumns []string) *bytes.Buffer {
+	return generateHTML(newTemplateLayer(layer, columns, geomColumns))
+}
+
+// Generate HTML for the template model of a layer, with a column per attribute or a row per attribute in the vertical layout
+func generateHTML(layer templateLayer) *bytes.Buffer {
 	buf := new(bytes.Buffer)
-	buf.WriteString(htmlStart)
-	log.Print("Generate HTML for layer: " + layer)
+	buf.WriteString(htmlDocumentStart(htmlStart, layer, mapserverText))
+	log.Print("Generate HTML for layer: " + layer.Name)
+	if layer.Layout == "vertical" {
+		generateVerticalHTML(buf, layer)
+		generateRelatedTablesHTML(buf, layer)
+		return buf
+	}
 	layerTemplate, err := template.New("layer").Parse(htmlLayer)
 	if err != nil {
 		log.Fatal(err)
 	}
 	layerReplace := map[string]interface{}{
-		"layer": template.HTML(layer),
+		"layer": template.HTML(mapserverText(layer.Title)),
 	}
 	err = layerTemplate.ExecuteTemplate(buf, "layer", layerReplace)
 	if err != nil {
@@ -181,15 +265,17 @@ func generateHTMLForLayer(layer string, columns []string, geomColumns []string)
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, column := range columns {
-		if checkColumn(column, geomColumns) {
-			columnHeadReplace := map[string]interface{}{
-				"column": template.HTML(column),
-			}
-			err = columnHeadTemplate.ExecuteTemplate(buf, "column", columnHeadReplace)
-			if err != nil {
-				log.Fatal(err)
-			}
+	for _, column := range layer.Columns {
+		open, close := layer.emptyCondition(column)
+		columnHeadReplace := map[string]interface{}{
+			"column": template.HTML(mapserverText(column.Header)),
+			"title":  template.HTMLAttr(htmlTitleAttribute(column.Description, mapserverText)),
+			"open":   template.HTML(open),
+			"close":  template.HTML(close),
+		}
+		err = columnHeadTemplate.ExecuteTemplate(buf, "column", columnHeadReplace)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 	buf.WriteString("\t\t\t</tr>\n\t\t\t<tr>\n")
@@ -197,21 +283,65 @@ func generateHTMLForLayer(layer string, columns []string, geomColumns []string)
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, column := range columns {
-		if checkColumn(column, geomColumns) {
-			columnRowReplace := map[string]interface{}{
-				"column": template.HTML(column),
-			}
-			err = columnRowTemplate.ExecuteTemplate(buf, "column", columnRowReplace)
-			if err != nil {
-				log.Fatal(err)
-			}
+	for _, column := range layer.Columns {
+		open, close := layer.emptyCondition(column)
+		columnRowReplace := map[string]interface{}{
+			"value": template.HTML(layer.htmlValue(column)),
+			"open":  template.HTML(open),
+			"close": template.HTML(close),
+		}
+		err = columnRowTemplate.ExecuteTemplate(buf, "column", columnRowReplace)
+		if err != nil {
+			log.Fatal(err)
 		}
 	}
 	buf.WriteString(htmlEnd)
+	generateRelatedTablesHTML(buf, layer)
 	return buf
 }
 
+// Generate the nested tables with related records and close the HTML
+func generateRelatedTablesHTML(buf *bytes.Buffer, layer templateLayer) {
+	for _, relation := range layer.Relations {
+		buf.WriteString(generateRelatedHTML(relation))
+	}
+	buf.WriteString(htmlBodyEnd)
+}
+
+// Generate the rows of the vertical HTML layout, with the header and value of an attribute on each row
+func generateVerticalHTML(buf *bytes.Buffer, layer templateLayer) {
+	layerTemplate, err := template.New("layer").Parse(htmlVerticalLayer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	layerReplace := map[string]interface{}{
+		"layer": template.HTML(mapserverText(layer.Title)),
+	}
+	err = layerTemplate.ExecuteTemplate(buf, "layer", layerReplace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rowTemplate, err := template.New("row").Parse(htmlVerticalRow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, column := range layer.Columns {
+		open, close := layer.emptyCondition(column)
+		rowReplace := map[string]interface{}{
+			"column": template.HTML(mapserverText(column.Header)),
+			"title":  template.HTMLAttr(htmlTitleAttribute(column.Description, mapserverText)),
+			"value":  template.HTML(layer.htmlValue(column)),
+			"open":   template.HTML(open),
+			"close":  template.HTML(close),
+		}
+		err = rowTemplate.ExecuteTemplate(buf, "row", rowReplace)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	buf.WriteString(htmlVerticalEnd)
+}
+
 // Check if column name should be included in HTML template
 func checkColumn(columnName string, geomColumns []string) bool {
 	badColumns := []string{"geom", "shape_len", "shape_leng", "shape_area"}
@@ -224,18 +354,6 @@ func checkColumn(columnName string, geomColumns []string) bool {
 	return true
 }
 
-// Write HTML to file
-func writeHTMLfile(layer string, htmlBuffer *bytes.Buffer) {
-	if _, err := os.Stat("output"); os.IsNotExist(err) {
-		os.Mkdir("output", 0777)
-	}
-	fileName := "output/" + layer + ".html"
-	errFile := ioutil.WriteFile(fileName, htmlBuffer.Bytes(), 0777)
-	if errFile != nil {
-		log.Fatal("Cannot create html file", errFile)
-	}
-}
-
 // Remove created temporary file
 func cleanup(gpkgFile *os.File, gpkgURLParam *string) {
 	if *gpkgURLParam != "" {
@@ -257,6 +375,10 @@ func programFinishedSuccesfully(startTime time.Time) {
 
 const htmlStart = "<!-- MapServer Template -->\n<html>\n\t<head>\n\t\t<title>GetFeatureInfo output</title>\n\t</head>\n\t<style type=\"text/css\">table.featureInfo, table.featureInfo td, table.featureInfo th { border: 1px solid #ddd; border-collapse: collapse; margin: 0; padding: 0; font-size: 90%; padding: .2em .1em; } table.featureInfo th { padding: .2em .2em; font-weight: bold; background: #eee; } table.featureInfo td { background: #fff; } table.featureInfo tr.odd td { background: #eee; } table.featureInfo caption { text-align: left; font-size: 100%; font-weight: bold; padding: .2em .2em; }</style>\n\t<body>\n\t\t<table class=\"featureInfo\">\n"
 const htmlLayer = "\t\t\t<caption class=\"featureInfo\">{{.layer}}</caption>\n\t\t\t<tr>\n"
-const htmlColumnHead = "\t\t\t\t<th>{{.column}}</th>\n"
-const htmlColumnRow = "\t\t\t\t<td>[{{.column}}]</td>\n"
-const htmlEnd = "\t\t\t</tr>\n\t\t</table>\n\t</body>\n</html>\n<!-- Generated by PDOK ( https://www.pdok.nl/ ) -->"
+const htmlColumnHead = "\t\t\t\t{{.open}}<th{{.title}}>{{.column}}</th>{{.close}}\n"
+const htmlColumnRow = "\t\t\t\t{{.open}}<td>{{.value}}</td>{{.close}}\n"
+const htmlEnd = "\t\t\t</tr>\n\t\t</table>\n"
+const htmlBodyEnd = "\t</body>\n</html>\n<!-- Generated by PDOK ( https://www.pdok.nl/ ) -->"
+const htmlVerticalLayer = "\t\t\t<caption class=\"featureInfo\">{{.layer}}</caption>\n"
+const htmlVerticalRow = "\t\t\t{{.open}}<tr>\n\t\t\t\t<th{{.title}}>{{.column}}</th>\n\t\t\t\t<td>{{.value}}</td>\n\t\t\t</tr>{{.close}}\n"
+const htmlVerticalEnd = "\t\t</table>\n"
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -61,3 +65,38 @@ func Test_checkColumn(t *testing.T) {
 func Test_programFinishedSuccesfully(t *testing.T) {
 	programFinishedSuccesfully(time.Now())
 }
+
+// Create a small Geopackage with one point layer for tests, the returned function removes it again
+func createTestGeopackage(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "test.gpkg"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	statements := []string{
+		"CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition TEXT NOT NULL, description TEXT)",
+		"INSERT INTO gpkg_spatial_ref_sys VALUES ('Amersfoort / RD New', 28992, 'EPSG', 28992, 'undefined', NULL)",
+		"CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE, description TEXT DEFAULT '', last_change DATETIME, min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER)",
+		"CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL)",
+		"CREATE TABLE putten (fid INTEGER PRIMARY KEY AUTOINCREMENT, geom POINT, naam TEXT, diepte REAL, bouwdatum DATE, actief BOOLEAN)",
+		"INSERT INTO gpkg_contents VALUES ('putten', 'features', 'Putten', 'Rioolputten', NULL, 150000, 450000, 150010, 450010, 28992)",
+		"INSERT INTO gpkg_geometry_columns VALUES ('putten', 'geom', 'POINT', 28992, 0, 0)",
+		"INSERT INTO putten (geom, naam, diepte, bouwdatum, actief) VALUES (X'4750000140710000010100000000000000804F02410000000040771B41', 'Put 1', 2.5, '2020-01-05', 1)",
+		"INSERT INTO putten (geom, naam, diepte, bouwdatum, actief) VALUES (X'4750000140710000010100000000000000D04F02410000000068771B41', 'Put 2', NULL, '2021-03-15', 0)",
+	}
+	for _, statement := range statements {
+		if _, err = db.Exec(statement); err != nil {
+			cleanup()
+			t.Fatal(err)
+		}
+	}
+	return db, cleanup
+}
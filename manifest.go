@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const manifestFileName = ".gpkg-to-featureinfo-manifest.json"
+const lockFileName = ".gpkg-to-featureinfo.lock"
+
+// Age after which a lock is taken over even when its run can't be checked, a run takes seconds to minutes
+const lockMaxAge = time.Hour
+
+// Files generated into an output directory by earlier runs
+type manifest struct {
+	Generated time.Time `json:"generated"`
+	Files     []string  `json:"files"`
+}
+
+// Read the manifest of an output directory, a missing manifest is treated as empty
+func readManifest(dir string) []string {
+	content, err := ioutil.ReadFile(filepath.Join(dir, manifestFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Cannot read manifest: ", err)
+	}
+	var m manifest
+	if err = json.Unmarshal(content, &m); err != nil {
+		log.Fatal("Cannot parse manifest: ", err)
+	}
+	return m.Files
+}
+
+// Write the manifest of an output directory
+func writeManifest(dir string, files []string) {
+	sort.Strings(files)
+	content, err := json.MarshalIndent(manifest{Generated: time.Now().UTC(), Files: files}, "", "  ")
+	if err != nil {
+		log.Fatal("Cannot create manifest: ", err)
+	}
+	writeFileAtomic(filepath.Join(dir, manifestFileName), content)
+}
+
+// Combine the files of an earlier run with the files written in this run
+func mergeManifest(previous []string, written []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, file := range append(previous, written...) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Delete files listed in the previous manifest that were not written in this run
+func pruneStaleFiles(dir string, previous []string, written []string) {
+	current := make(map[string]bool)
+	for _, file := range written {
+		current[file] = true
+	}
+	for _, file := range previous {
+		if current[file] {
+			continue
+		}
+		fileName, ok := manifestFileInDir(dir, file)
+		if !ok {
+			log.Printf("Manifest entry %s is not a file in the output directory and is not pruned", file)
+			continue
+		}
+		err := os.Remove(fileName)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal("Cannot prune stale file: ", err)
+		}
+		log.Println("Pruned stale file: " + fileName)
+		removeEmptyDirs(dir, filepath.Dir(fileName))
+	}
+}
+
+// Path of a file in the manifest, not ok for absolute paths and paths outside the output directory
+func manifestFileInDir(dir string, file string) (string, bool) {
+	if file == "" || filepath.IsAbs(filepath.FromSlash(file)) || strings.Contains(file, "..") {
+		return "", false
+	}
+	fileName := filepath.Join(dir, filepath.FromSlash(file))
+	relative, err := filepath.Rel(dir, fileName)
+	if err != nil || relative == "." || strings.HasPrefix(relative, "..") || filepath.IsAbs(relative) {
+		return "", false
+	}
+	return fileName, true
+}
+
+// Remove directories left empty by pruning, up to the output directory
+func removeEmptyDirs(root string, dir string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// Run holding the lock of an output directory
+type outputLock struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+}
+
+// Claim an output directory, so two runs can't write the same directory.
+// The lock holds the process ID, host and start time of the run, a lock of a run that is no longer active is taken over.
+func lockOutputDir(dir string) {
+	lockFile := filepath.Join(dir, lockFileName)
+	file, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, filePermissions)
+	if os.IsExist(err) && staleLock(lockFile) {
+		log.Print("Taking over the lock of a run that is no longer active: " + lockFile)
+		os.Remove(lockFile)
+		file, err = os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, filePermissions)
+	}
+	if os.IsExist(err) {
+		log.Fatalf("Output directory is locked by another run (%s)", lockFile)
+	}
+	if err != nil {
+		log.Fatal("Cannot create lock file: ", err)
+	}
+	hostname, _ := os.Hostname()
+	content, err := json.Marshal(outputLock{PID: os.Getpid(), Hostname: hostname, Started: time.Now().UTC()})
+	if err == nil {
+		_, err = file.Write(content)
+	}
+	file.Close()
+	if err != nil {
+		log.Fatal("Cannot write lock file: ", err)
+	}
+}
+
+// Whether a lock file was left by a run that is no longer active. A lock older than lockMaxAge is stale,
+// also when it can't be read or comes from another host. A lock of this host is stale when it has the process ID of this run,
+// which happens in containers where every run is process 1, or when its process is gone.
+func staleLock(lockFile string) bool {
+	info, err := os.Stat(lockFile)
+	if err != nil {
+		return false
+	}
+	if time.Since(info.ModTime()) > lockMaxAge {
+		return true
+	}
+	content, err := ioutil.ReadFile(lockFile)
+	if err != nil {
+		return false
+	}
+	var lock outputLock
+	if err = json.Unmarshal(content, &lock); err != nil {
+		return false
+	}
+	if time.Since(lock.Started) > lockMaxAge {
+		return true
+	}
+	if hostname, _ := os.Hostname(); lock.Hostname != hostname {
+		return false
+	}
+	return lock.PID == os.Getpid() || !processRunning(lock.PID)
+}
+
+// Whether a process is running. Windows only finds running processes, elsewhere signal 0 checks that it exists.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		process.Release()
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// Release an output directory claimed with lockOutputDir
+func unlockOutputDir(dir string) {
+	if err := os.Remove(filepath.Join(dir, lockFileName)); err != nil {
+		log.Print("Could not remove lock file: ", err)
+	}
+}
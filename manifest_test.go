@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_dirSinkPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := openOutputSink(dir, true)
+	sink.write("afvalwater/layer1.html", []byte("layer1"))
+	sink.write("afvalwater/layer2.html", []byte("layer2"))
+	sink.close()
+	sink = openOutputSink(dir, true)
+	sink.write("afvalwater/layer1.html", []byte("layer1 v2"))
+	sink.close()
+	if _, err = os.Stat(filepath.Join(dir, "afvalwater", "layer2.html")); !os.IsNotExist(err) {
+		t.Error("Stale file layer2.html was not pruned.")
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, "afvalwater", "layer1.html"))
+	if err != nil || string(content) != "layer1 v2" {
+		t.Errorf("Unexpected content for layer1.html: %s", content)
+	}
+	if files := readManifest(dir); !reflect.DeepEqual(files, []string{"afvalwater/layer1.html"}) {
+		t.Errorf("Unexpected manifest: %v", files)
+	}
+	if _, err = os.Stat(filepath.Join(dir, lockFileName)); !os.IsNotExist(err) {
+		t.Error("Lock file was not removed.")
+	}
+}
+
+func Test_mergeManifest(t *testing.T) {
+	result := mergeManifest([]string{"a.html", "b.html"}, []string{"b.html", "c.html"})
+	if !reflect.DeepEqual(result, []string{"a.html", "b.html", "c.html"}) {
+		t.Errorf("Unexpected merge result: %v", result)
+	}
+}
+
+func Test_lockOutputDirStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lockFile := filepath.Join(dir, lockFileName)
+	hostname, _ := os.Hostname()
+	writeLock := func(lock outputLock) {
+		content, _ := json.Marshal(lock)
+		if err := ioutil.WriteFile(lockFile, content, filePermissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		lock  outputLock
+		stale bool
+	}{
+		{outputLock{PID: 999999999, Hostname: hostname, Started: time.Now()}, true},
+		{outputLock{PID: os.Getpid(), Hostname: hostname, Started: time.Now()}, true},
+		{outputLock{PID: os.Getppid(), Hostname: hostname, Started: time.Now()}, false},
+		{outputLock{PID: 1, Hostname: hostname + "-other", Started: time.Now()}, false},
+		{outputLock{PID: 1, Hostname: hostname + "-other", Started: time.Now().Add(-2 * lockMaxAge)}, true},
+	}
+	for _, test := range tests {
+		writeLock(test.lock)
+		if stale := staleLock(lockFile); stale != test.stale {
+			t.Errorf("Lock %+v was stale %v, expected %v", test.lock, stale, test.stale)
+		}
+	}
+	writeLock(outputLock{PID: 999999999, Hostname: hostname, Started: time.Now()})
+	lockOutputDir(dir)
+	content, _ := ioutil.ReadFile(lockFile)
+	var lock outputLock
+	if err = json.Unmarshal(content, &lock); err != nil || lock.PID != os.Getpid() || lock.Hostname != hostname {
+		t.Errorf("Lock was not taken over: %s", content)
+	}
+	unlockOutputDir(dir)
+}
+
+func Test_pruneStaleFilesOutsideDir(t *testing.T) {
+	parent, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "output")
+	outside := filepath.Join(parent, "outside.html")
+	for _, file := range []string{outside, filepath.Join(dir, "stale.html")} {
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err = ioutil.WriteFile(file, []byte("x"), filePermissions); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneStaleFiles(dir, []string{"../outside.html", outside, "stale.html"}, nil)
+	if _, err = os.Stat(outside); err != nil {
+		t.Error("File outside the output directory was pruned.")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "stale.html")); !os.IsNotExist(err) {
+		t.Error("Stale file in the output directory was not pruned.")
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"html"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Options that decide how the template model of a layer is built, shared by the commands that generate templates
+type templateOptions struct {
+	geometrySummary    string
+	locale             string
+	configPath         string
+	detectLinks        bool
+	layout             string
+	hideEmpty          bool
+	emptyPlaceholder   string
+	qgisStyle          bool
+	featureCatalogue   bool
+	relatedTables      bool
+	detectJSON         bool
+	detectPII          bool
+	strict             bool
+	profile            bool
+	profileNullRatio   float64
+	profileMinDistinct int
+	exclude            string
+	prettyHeaders      bool
+	lang               string
+	config             *templateConfig
+}
+
+// Register the template options on a flag set
+func registerTemplateFlags(flags *flag.FlagSet) *templateOptions {
+	options := &templateOptions{}
+	flags.StringVar(&options.geometrySummary, "geometry-summary", "", "Comma separated geometry information to add to the templates: type, coordinate and measure (area or length)")
+	flags.StringVar(&options.locale, "locale", "", "Locale to format booleans, numbers and dates in: "+strings.Join(localeNames(), ", ")+" (default the locale of the config, without one values are shown as stored)")
+	flags.BoolVar(&options.detectLinks, "detect-links", false, "Show columns with URLs, email addresses or image URLs as links or images, detected from a sample of the values. Email columns are personal data, see -detect-personal-data")
+	flags.StringVar(&options.layout, "layout", "horizontal", "Layout of the HTML templates: horizontal with a column per attribute or vertical with a row per attribute")
+	flags.BoolVar(&options.hideEmpty, "hide-empty", false, "Leave attributes with an empty value out of the HTML templates")
+	flags.StringVar(&options.emptyPlaceholder, "empty-placeholder", "", "Text shown in the HTML templates for an empty value, for example –")
+	flags.BoolVar(&options.qgisStyle, "qgis-style", true, "Use the aliases, hidden fields, field order and value maps of the default QGIS style in the layer_styles table")
+	flags.BoolVar(&options.featureCatalogue, "feature-catalogue", true, "Use the headers, descriptions, units and listed values of an ISO 19110 feature catalogue in gpkg_metadata")
+	flags.BoolVar(&options.relatedTables, "related-tables", false, "Add nested tables with the related records of the Related Tables extension to the HTML templates")
+	flags.BoolVar(&options.detectJSON, "detect-json", false, "Show the keys of text columns holding JSON objects in a nested table, detected from a sample of the values")
+	flags.BoolVar(&options.detectPII, "detect-personal-data", true, "Leave out columns with personal data (BSN, email, phone, IBAN), detected from their names and a sample of the values")
+	flags.BoolVar(&options.strict, "strict", false, "Fail when a layer has columns with personal data instead of leaving them out")
+	flags.BoolVar(&options.profile, "profile", false, "Profile the columns of every layer and hide the columns that are empty or constant")
+	flags.Float64Var(&options.profileNullRatio, "profile-null-ratio", 1, "With -profile, hide columns with at least this ratio of empty values")
+	flags.IntVar(&options.profileMinDistinct, "profile-min-distinct", 2, "With -profile, hide columns with fewer distinct values")
+	flags.StringVar(&options.exclude, "exclude", "", "Comma separated columns or column classes to leave out, like class:technical (classes: "+
+		strings.Join([]string{classPrimaryKey, classForeignKey, classTechnical, classGeometryDerived, classDescriptive}, ", ")+")")
+	flags.BoolVar(&options.prettyHeaders, "pretty-headers", false, "Make readable headers from the names of columns without an alias, like Oppervlakte (m²) for OPP_M2")
+	flags.StringVar(&options.lang, "lang", "", "Comma separated languages to make the templates in, like nl,en, with the translations of the config. Several languages give a set of files per language")
+	flags.StringVar(&options.configPath, "config", "", "JSON file with settings per layer and column")
+	return options
+}
+
+// Check the template options after the flags are parsed
+func (o *templateOptions) check() {
+	for _, item := range o.geometrySummaryItems() {
+		if item != "type" && item != "coordinate" && item != "measure" {
+			log.Fatal("Error: unknown geometry-summary item " + item)
+		}
+	}
+	if o.layout != "horizontal" && o.layout != "vertical" {
+		log.Fatal("Error: unknown layout " + o.layout + ", use horizontal or vertical")
+	}
+	if o.profileNullRatio < 0 || o.profileNullRatio > 1 {
+		log.Fatal("Error: profile-null-ratio must be between 0 and 1")
+	}
+	if o.hideEmpty && o.emptyPlaceholder != "" {
+		log.Fatal("Error: use either hide-empty or empty-placeholder")
+	}
+	o.config = readConfig(o.configPath)
+	if o.locale == "" {
+		o.locale = o.config.Locale
+	}
+	if o.locale != "" {
+		checkLocale(o.locale)
+	}
+	checkColumnRules(o.excludeRules())
+	checkColumnRules(o.config.Exclude)
+	checkColumnRules(o.config.Include)
+	for _, layer := range o.config.Layers {
+		checkColumnRules(layer.Exclude)
+		checkColumnRules(layer.Include)
+	}
+	checkLanguages(o.languages(), o.config)
+}
+
+// Exclude rules given with -exclude
+func (o *templateOptions) excludeRules() []string {
+	var rules []string
+	for _, rule := range strings.Split(o.exclude, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Exclude and include rules for a layer, from -exclude and from the config for all layers and for the layer
+func (o *templateOptions) columnRules(layer string) ([]string, []string) {
+	exclude := o.excludeRules()
+	var include []string
+	if o.config != nil {
+		exclude = append(append(exclude, o.config.Exclude...), o.config.Layers[layer].Exclude...)
+		include = append(append(include, o.config.Include...), o.config.Layers[layer].Include...)
+	}
+	return exclude, include
+}
+
+// Build the template model of a layer from the Geopackage
+func buildTemplateLayer(layer string, geopackage *sql.DB, geomColumns []string, options *templateOptions) templateLayer {
+	model := newTemplateLayer(layer, getPropertiesFromLayer(layer, geopackage), geomColumns)
+	tableColumns := getColumnInfoFromLayer(layer, geopackage)
+	model.setColumnTypes(tableColumns)
+	applyColumnClasses(&model, geopackage, options)
+	applyPersonalDataPolicy(&model, geopackage, options)
+	model.Layout, model.HideEmpty, model.EmptyPlaceholder = options.layout, options.hideEmpty, options.emptyPlaceholder
+	if options.featureCatalogue {
+		if attributes := getFeatureCatalogueForLayer(layer, geopackage); attributes != nil {
+			applyFeatureCatalogue(&model, attributes, geopackage)
+		}
+	}
+	if options.qgisStyle {
+		if style := getQGISStyleForLayer(layer, geopackage); style != nil {
+			applyQGISStyle(&model, style, geopackage, options.config)
+		}
+	}
+	applyColumnConfig(&model, options.config)
+	applyPrettyHeaders(&model, options)
+	applyProfile(&model, geopackage, options)
+	applyValueFormats(&model, options.locale, options.config)
+	applyLinks(&model, geopackage, options)
+	applyCodeLists(&model, geopackage, options)
+	applyJSONColumns(&model, geopackage, options)
+	applyComputedFields(&model, tableColumns, options.config)
+	if options.relatedTables {
+		model.Relations = getRelationsForLayer(layer, geopackage, geomColumns, options)
+	}
+	if column, ok := getGeometryColumnPerLayer(geopackage)[layer]; ok {
+		model.GeometryColumn, model.GeometryType = column.Name, column.Type
+		addGeometrySummary(&model, options.geometrySummaryItems())
+	}
+	return model
+}
+
+// Layer as it is rendered into templates, shared by all output formats
+type templateLayer struct {
+	Name             string
+	Title            string
+	GeometryColumn   string
+	GeometryType     string
+	Locale           string
+	Layout           string
+	HideEmpty        bool
+	EmptyPlaceholder string
+	Columns          []templateColumn
+	Relations        []templateRelation
+	ViewColumns      []viewColumn
+	PersonalData     []string
+	Excluded         []string
+	Classes          map[string]string
+	Language         string
+	PageTitle        string
+	Features         int
+	Profile          []columnProfile
+}
+
+// Column of a layer as it is rendered into templates. Columns either show an attribute value (Name),
+// a fixed text (Static) or the result of a MapServer tag (Tag). Format decides how attribute values are shown,
+// Link shows them as a link or an image in HTML and CodeList replaces codes by their labels.
+// Description explains the column, Unit is shown after the value and Nested holds the values of a JSON column.
+// Parts combine the values of several columns into one computed field and Class tells what kind of column it is.
+// Formatted is the column of the SQL view with the formatted value, for dates MapServer can't format itself.
+// UIText is the key of the fixed text the header is, for headers that are translated like the page title.
+type templateColumn struct {
+	Name        string
+	Header      string
+	Type        string
+	Static      string
+	Tag         string
+	Format      *valueFormat
+	Link        *linkFormat
+	CodeList    *codeList
+	Description string
+	Unit        string
+	Nested      []templateColumn
+	Parts       []computedPart
+	Class       string
+	Formatted   string
+	UIText      string
+}
+
+// Column the SQL view of a layer adds, for values MapServer can't derive itself like the keys of JSON or formatted dates
+type viewColumn struct {
+	Name       string
+	Expression string
+}
+
+// Statement selecting the features of a layer with the columns of its SQL view
+func (l templateLayer) selectSQL() string {
+	statement := "SELECT *"
+	for _, column := range l.ViewColumns {
+		statement += ", " + column.Expression + " AS " + quoteIdentifier(column.Name)
+	}
+	return statement + " FROM " + quoteIdentifier(l.Name)
+}
+
+// SQL file creating the view with the columns MapServer can't derive, the layer in MapServer or GeoServer has to use it
+func viewFiles(layer templateLayer, naming outputNaming) []generatedFile {
+	if len(layer.ViewColumns) == 0 {
+		return nil
+	}
+	view := layer.Name + "_view"
+	content := "-- View on " + layer.Name + " with the values of its JSON columns and formatted dates, for MapServer and GeoServer to serve the layer from.\n" +
+		"-- Create it in the Geopackage with: sqlite3 " + naming.dataset + ".gpkg < " + view + ".sql\n" +
+		"-- or use the SELECT as the DATA of the MapServer layer.\n" +
+		"CREATE VIEW " + quoteIdentifier(view) + " AS " + layer.selectSQL() + ";\n"
+	log.Printf("The templates of layer %s use columns of a SQL view, serve the layer from the view in %s.sql", layer.Name, view)
+	return []generatedFile{{name: outputFileName(naming.pattern, naming.dataset, view, "sql"), content: []byte(content)}}
+}
+
+// Build the template model of a layer, with the columns that pass checkColumn
+func newTemplateLayer(layer string, columns []string, geomColumns []string) templateLayer {
+	model := templateLayer{Name: layer, Title: layer}
+	for _, column := range columns {
+		if checkColumn(column, geomColumns) {
+			model.Columns = append(model.Columns, templateColumn{Name: column, Header: column})
+		}
+	}
+	return model
+}
+
+// Set the declared types of the columns of a layer
+func (l *templateLayer) setColumnTypes(columns []columnInfo) {
+	types := make(map[string]string)
+	for _, column := range columns {
+		types[column.Name] = column.Type
+	}
+	for i := range l.Columns {
+		if l.Columns[i].Name != "" {
+			l.Columns[i].Type = types[l.Columns[i].Name]
+		}
+	}
+}
+
+// Put the columns in an order, columns that are not in it follow in their current order
+func (l *templateLayer) orderColumns(order []string) {
+	if len(order) == 0 {
+		return
+	}
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+	sort.SliceStable(l.Columns, func(i, j int) bool {
+		pi, oki := position[l.Columns[i].Name]
+		pj, okj := position[l.Columns[j].Name]
+		if oki && okj {
+			return pi < pj
+		}
+		return oki && !okj
+	})
+}
+
+// MapServer expression for the value of a column, escaped for the output format (html, json, xml, csv or none)
+func mapserverValue(column templateColumn, escape string) string {
+	if column.Static != "" {
+		return escapeStatic(column.Static, escape)
+	}
+	if column.Tag != "" {
+		return column.Tag
+	}
+	if len(column.Parts) > 0 {
+		return mapserverComputed(column, escape)
+	}
+	if len(column.Nested) > 0 && escape == "html" {
+		return mapserverNested(column)
+	}
+	if column.CodeList != nil {
+		return mapserverCodeList(column, escape)
+	}
+	if column.Link != nil && escape == "html" {
+		return mapserverLink(column)
+	}
+	if column.Format.hasLabels() {
+		return mapserverBooleanLabels(column, escape)
+	}
+	if column.Formatted != "" {
+		column.Name = column.Formatted
+	}
+	attributes := ""
+	if column.Format != nil && column.Format.Precision != nil {
+		attributes += precisionAttribute(*column.Format.Precision)
+	}
+	if column.Unit != "" {
+		attributes += ` format="$value ` + escapeStatic(column.Unit, escape) + `" nullformat=""`
+	}
+	if escape == "csv" {
+		attributes += ` pattern='` + csvValuePattern + `'`
+	}
+	if escape == "html" && attributes == "" {
+		return "[" + column.Name + "]"
+	}
+	return `[item name="` + column.Name + `"` + attributes + ` escape="` + mapserverEscape(escape) + `"]`
+}
+
+// Column without value formatting, links, code lists and units, for formats that need the values as they are stored
+func (c templateColumn) unformatted() templateColumn {
+	c.Format = nil
+	c.Link = nil
+	c.CodeList = nil
+	c.Unit = ""
+	c.Nested = nil
+	c.Formatted = ""
+	var parts []computedPart
+	for _, part := range c.Parts {
+		column := part.Column.unformatted()
+		part.Column = &column
+		parts = append(parts, part)
+	}
+	c.Parts = parts
+	return c
+}
+
+// Title attribute with the description of a column, shown as tooltip on the header, escape escapes it for the template language
+func htmlTitleAttribute(description string, escape func(text string) string) string {
+	if description == "" {
+		return ""
+	}
+	return ` title="` + escape(description) + `"`
+}
+
+// Brackets in fixed text as character references, so MapServer doesn't read them as tags
+var mapserverBrackets = strings.NewReplacer("[", "&#91;", "]", "&#93;")
+
+// Fixed text like a header or a title in a MapServer HTML template, escaped for HTML and with brackets
+// as character references so MapServer doesn't read them as tags
+func mapserverText(text string) string {
+	return mapserverBrackets.Replace(html.EscapeString(text))
+}
+
+// MapServer escape attribute for an output format
+func mapserverEscape(escape string) string {
+	switch escape {
+	case "html", "xml", "json", "url":
+		return escape
+	}
+	return "none"
+}
+
+// Escape a fixed text for an output format, brackets never reach MapServer as they are
+func escapeStatic(text string, escape string) string {
+	switch escape {
+	case "html", "xml":
+		return mapserverText(text)
+	case "json":
+		return strings.NewReplacer("[", `\u005b`, "]", `\u005d`).Replace(jsonEscape(text))
+	case "csv":
+		return mapserverBrackets.Replace(strings.Replace(text, `"`, `""`, -1))
+	}
+	return mapserverBrackets.Replace(text)
+}
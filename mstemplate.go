@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Attribute values and geometry of a feature, as MapServer passes them to a template, and the rendered one-to-many joins
+type templateFeature struct {
+	Values   map[string]string
+	Geometry *geometry
+	Joins    map[string]string
+}
+
+// First line of non HTML templates, MapServer only accepts templates that contain the magic string
+const templateMagic = "// MapServer Template\n"
+
+var templateAttributeRegexp = regexp.MustCompile(`(\w+)\s*=\s*("([^"]*)"|'([^']*)'|[^\s\]]+)`)
+
+// Render a MapServer query template for a set of features, like MapServer does for GetFeatureInfo.
+// Templates with [resultset] or [feature] blocks are rendered once, other templates once per feature.
+// A first line with the magic string as a // comment, used by non HTML templates, is left out.
+func renderMapserverTemplate(template string, layer string, features []templateFeature) string {
+	if strings.HasPrefix(template, "//") {
+		if end := strings.IndexByte(template, '\n'); end >= 0 && strings.Contains(template[:end], "MapServer Template") {
+			template = template[end+1:]
+		}
+	}
+	if strings.Contains(template, "[resultset") || strings.Contains(template, "[feature") {
+		output := replaceBlocks(template, "resultset", func(attributes map[string]string, content string) string {
+			if name, ok := attributes["layer"]; (ok && name != layer) || len(features) == 0 {
+				return ""
+			}
+			return content
+		})
+		return replaceBlocks(output, "feature", func(attributes map[string]string, content string) string {
+			var buf strings.Builder
+			for i, feature := range features {
+				rendered := renderTemplateFeature(content, feature)
+				if trimLast, ok := attributes["trimlast"]; ok && i == len(features)-1 {
+					if last := strings.LastIndex(rendered, trimLast); last >= 0 && trimLast != "" {
+						rendered = rendered[:last] + rendered[last+len(trimLast):]
+					}
+				}
+				buf.WriteString(rendered)
+			}
+			return buf.String()
+		})
+	}
+	var buf strings.Builder
+	for _, feature := range features {
+		buf.WriteString(renderTemplateFeature(template, feature))
+	}
+	return buf.String()
+}
+
+// Render the [if], [item] and attribute tags of a template for one feature
+func renderTemplateFeature(template string, feature templateFeature) string {
+	output := replaceBlocks(template, "if", func(attributes map[string]string, content string) string {
+		if evaluateTemplateCondition(attributes, feature) {
+			return renderTemplateFeature(content, feature)
+		}
+		return ""
+	})
+	var buf strings.Builder
+	for {
+		start := strings.IndexByte(output, '[')
+		if start < 0 {
+			break
+		}
+		end := findTagEnd(output, start)
+		if end < 0 {
+			buf.WriteString(output[:start+1])
+			output = output[start+1:]
+			continue
+		}
+		buf.WriteString(output[:start])
+		buf.WriteString(renderTemplateTag(output[start:end+1], feature))
+		output = output[end+1:]
+	}
+	buf.WriteString(output)
+	return buf.String()
+}
+
+// Render a single tag, unknown tags are left as they are
+func renderTemplateTag(tag string, feature templateFeature) string {
+	body := tag[1 : len(tag)-1]
+	if strings.HasPrefix(body, "item ") {
+		return renderTemplateItem(parseTemplateAttributes(body), feature)
+	}
+	if strings.HasPrefix(body, "shp") && feature.Geometry != nil {
+		if value, ok := renderShapeTag(body, feature.Geometry); ok {
+			return value
+		}
+	}
+	if name := strings.TrimPrefix(body, "join_"); name != body {
+		return feature.Joins[name]
+	}
+	if value, ok := feature.Values[body]; ok {
+		return html.EscapeString(value)
+	}
+	if name := strings.TrimSuffix(body, "_raw"); name != body {
+		if value, ok := feature.Values[name]; ok {
+			return value
+		}
+	}
+	if name := strings.TrimSuffix(body, "_esc"); name != body {
+		if value, ok := feature.Values[name]; ok {
+			return url.QueryEscape(value)
+		}
+	}
+	return tag
+}
+
+// Render an [item] tag
+func renderTemplateItem(attributes map[string]string, feature templateFeature) string {
+	value, ok := feature.Values[attributes["name"]]
+	if !ok {
+		return ""
+	}
+	if value == "" {
+		return attributes["nullformat"]
+	}
+	if pattern, ok := attributes["pattern"]; ok {
+		if matched, err := regexp.MatchString(pattern, value); err != nil || !matched {
+			return ""
+		}
+	}
+	if precision, ok := attributes["precision"]; ok {
+		if digits, err := strconv.Atoi(precision); err == nil {
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				value = strconv.FormatFloat(number, 'f', digits, 64)
+			}
+		}
+	}
+	if attributes["commify"] == "true" {
+		value = commify(value)
+	}
+	if attributes["uc"] == "true" {
+		value = strings.ToUpper(value)
+	}
+	if attributes["lc"] == "true" {
+		value = strings.ToLower(value)
+	}
+	switch attributes["escape"] {
+	case "url":
+		value = url.QueryEscape(value)
+	case "json":
+		value = jsonEscape(value)
+	case "xml", "html", "":
+		value = html.EscapeString(value)
+	}
+	if format, ok := attributes["format"]; ok {
+		value = strings.Replace(format, "$value", value, -1)
+	}
+	return value
+}
+
+// Render the shape tags [shpxy], [shpmidx] and [shpmidy]
+func renderShapeTag(body string, geom *geometry) (string, bool) {
+	name := strings.Fields(body)[0]
+	attributes := parseTemplateAttributes(body)
+	precision := -1
+	if digits, err := strconv.Atoi(attributes["precision"]); err == nil {
+		precision = digits
+	}
+	formatNumber := func(number float64) string {
+		return strconv.FormatFloat(number, 'f', precision, 64)
+	}
+	switch name {
+	case "shpxy":
+		xf, ok := attributes["xf"]
+		if !ok {
+			xf = " "
+		}
+		cs, ok := attributes["cs"]
+		if !ok {
+			cs = ","
+		}
+		var parts []string
+		for _, part := range shapeParts(*geom) {
+			var coordinates []string
+			for _, p := range part {
+				coordinates = append(coordinates, attributes["xh"]+formatNumber(p.X)+xf+attributes["yh"]+formatNumber(p.Y)+attributes["yf"])
+			}
+			parts = append(parts, attributes["ph"]+strings.Join(coordinates, cs)+attributes["pf"])
+		}
+		return attributes["sh"] + strings.Join(parts, attributes["ps"]) + attributes["sf"], true
+	case "shpmidx", "shpmidy":
+		env, ok := geom.envelope()
+		if !ok {
+			return "", true
+		}
+		if name == "shpmidx" {
+			return formatNumber((env.MinX + env.MaxX) / 2), true
+		}
+		return formatNumber((env.MinY + env.MaxY) / 2), true
+	}
+	return "", false
+}
+
+// Split a geometry in parts like MapServer does: a part per line or ring, multi points form one part
+func shapeParts(geom geometry) [][]point {
+	switch geom.Type {
+	case wkbPoint, wkbLineString:
+		return [][]point{geom.Coordinates}
+	case wkbPolygon:
+		return geom.Rings
+	case wkbMultiPoint:
+		var points []point
+		geom.eachPoint(func(p point) {
+			points = append(points, p)
+		})
+		return [][]point{points}
+	}
+	var parts [][]point
+	for _, part := range geom.Parts {
+		parts = append(parts, shapeParts(part)...)
+	}
+	return parts
+}
+
+// Evaluate the condition of an [if] tag
+func evaluateTemplateCondition(attributes map[string]string, feature templateFeature) bool {
+	value, exists := feature.Values[attributes["name"]]
+	expected := attributes["value"]
+	switch attributes["oper"] {
+	case "neq":
+		return value != expected
+	case "isset":
+		return exists && value != ""
+	case "isnull":
+		return !exists || value == ""
+	case "lt", "gt", "le", "ge":
+		left, errLeft := strconv.ParseFloat(value, 64)
+		right, errRight := strconv.ParseFloat(expected, 64)
+		if errLeft != nil || errRight != nil {
+			return false
+		}
+		switch attributes["oper"] {
+		case "lt":
+			return left < right
+		case "gt":
+			return left > right
+		case "le":
+			return left <= right
+		}
+		return left >= right
+	}
+	return value == expected
+}
+
+// Replace every [name ...]content[/name] block, taking nested blocks of the same name into account
+func replaceBlocks(template string, name string, replace func(attributes map[string]string, content string) string) string {
+	open := "[" + name
+	closing := "[/" + name + "]"
+	var buf strings.Builder
+	for {
+		start := indexOpenTag(template, open)
+		if start < 0 {
+			break
+		}
+		tagEnd := findTagEnd(template, start)
+		if tagEnd < 0 {
+			break
+		}
+		contentStart := tagEnd + 1
+		depth := 1
+		position := contentStart
+		contentEnd := -1
+		for depth > 0 {
+			nextOpen := indexOpenTag(template[position:], open)
+			nextClose := strings.Index(template[position:], closing)
+			if nextClose < 0 {
+				break
+			}
+			if nextOpen >= 0 && nextOpen < nextClose {
+				depth++
+				position += nextOpen + len(open)
+				continue
+			}
+			depth--
+			if depth == 0 {
+				contentEnd = position + nextClose
+			}
+			position += nextClose + len(closing)
+		}
+		if contentEnd < 0 {
+			break
+		}
+		attributes := parseTemplateAttributes(template[start+1 : tagEnd])
+		buf.WriteString(template[:start])
+		buf.WriteString(replace(attributes, template[contentStart:contentEnd]))
+		template = template[contentEnd+len(closing):]
+	}
+	buf.WriteString(template)
+	return buf.String()
+}
+
+// Find an opening tag, making sure [feature doesn't match [features
+func indexOpenTag(template string, open string) int {
+	offset := 0
+	for {
+		i := strings.Index(template[offset:], open)
+		if i < 0 {
+			return -1
+		}
+		next := offset + i + len(open)
+		if next < len(template) && (template[next] == ']' || template[next] == ' ' || template[next] == '\t' || template[next] == '\n') {
+			return offset + i
+		}
+		offset = next
+	}
+}
+
+// Find the closing bracket of a tag, skipping brackets in quoted attribute values
+func findTagEnd(template string, start int) int {
+	var quote byte
+	for i := start + 1; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			return -1
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// Parse the attributes of a tag like: item name="naam" format="$value"
+func parseTemplateAttributes(body string) map[string]string {
+	attributes := make(map[string]string)
+	for _, match := range templateAttributeRegexp.FindAllStringSubmatch(body, -1) {
+		value := match[2]
+		if strings.HasPrefix(value, `"`) {
+			value = match[3]
+		} else if strings.HasPrefix(value, "'") {
+			value = match[4]
+		}
+		attributes[strings.ToLower(match[1])] = value
+	}
+	return attributes
+}
+
+// Escape a value for use inside a JSON string
+func jsonEscape(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
+
+// Add thousands separators to a number
+func commify(value string) string {
+	integer, fraction := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		integer, fraction = value[:i], value[i:]
+	}
+	sign := ""
+	if strings.HasPrefix(integer, "-") {
+		sign, integer = "-", integer[1:]
+	}
+	for i := len(integer) - 3; i > 0; i -= 3 {
+		integer = integer[:i] + "," + integer[i:]
+	}
+	return sign + integer + fraction
+}
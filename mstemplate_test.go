@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func Test_renderMapserverTemplate(t *testing.T) {
+	features := []templateFeature{
+		{Values: map[string]string{"naam": "Put <1>", "diepte": "2.456", "type": ""}},
+		{Values: map[string]string{"naam": "Put 2", "diepte": "", "type": "kolk"}},
+	}
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"<td>[naam]</td>", "<td>Put &lt;1&gt;</td><td>Put 2</td>"},
+		{"[naam_raw];", "Put <1>;Put 2;"},
+		{`[item name="diepte" precision="1" nullformat="-"];`, "2.5;-;"},
+		{`[item name="naam" format="<b>$value</b>" escape="none"];`, "<b>Put <1></b>;<b>Put 2</b>;"},
+		{`[if name="type" oper="neq" value=""]type=[type][/if];`, ";type=kolk;"},
+		{`[if name="diepte" oper="isset"][if name="diepte" oper="gt" value="2"]diep[/if][/if];`, "diep;;"},
+		{`[[resultset layer="putten"][feature trimlast=","]"[naam]",[/feature][/resultset]]`, `["Put &lt;1&gt;","Put 2"]`},
+		{`[resultset layer="leidingen"][feature][naam][/feature][/resultset]`, ""},
+		{`[item name="naam" escape="json"]|`, `Put <1>|Put 2|`},
+	}
+	for _, test := range tests {
+		result := renderMapserverTemplate(test.template, "putten", features)
+		if result != test.expected {
+			t.Errorf("Template %s gave %s, expected %s.", test.template, result, test.expected)
+		}
+	}
+}
+
+func Test_commify(t *testing.T) {
+	for value, expected := range map[string]string{"1234567.89": "1,234,567.89", "-1000": "-1,000", "999": "999"} {
+		if result := commify(value); result != expected {
+			t.Errorf("Commify %s gave %s, expected %s.", value, result, expected)
+		}
+	}
+}
+
+func Test_renderShapeTag(t *testing.T) {
+	feature := templateFeature{Geometry: &geometry{Type: wkbLineString, Coordinates: []point{{0, 0}, {10, 5}}}}
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`[shpxy precision="1" xf=", " cs="; "]`, "0.0, 0.0; 10.0, 5.0"},
+		{`[shpmidx] [shpmidy precision="2"]`, "5 2.50"},
+	}
+	for _, test := range tests {
+		result := renderMapserverTemplate(test.template, "leidingen", []templateFeature{feature})
+		if result != test.expected {
+			t.Errorf("Template %s gave %s, expected %s.", test.template, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultOutput = "output"
+const defaultFileNamePattern = "{layer}.{format}"
+
+const dirPermissions = 0755
+const filePermissions = 0644
+
+// Destination for generated files, either a directory or an archive
+type outputSink interface {
+	write(name string, content []byte)
+	close()
+}
+
+// Open the output sink matching the output parameter
+func openOutputSink(output string, prune bool) outputSink {
+	switch {
+	case output == "-":
+		log.Println("Writing tar.gz archive to stdout")
+		return newTarGzSink(os.Stdout, nil)
+	case strings.HasSuffix(output, ".zip"):
+		log.Println("Writing zip archive: " + output)
+		return newZipSink(createOutputFile(output))
+	case strings.HasSuffix(output, ".tar.gz"), strings.HasSuffix(output, ".tgz"):
+		log.Println("Writing tar.gz archive: " + output)
+		file := createOutputFile(output)
+		return newTarGzSink(file, file)
+	default:
+		log.Println("Writing to directory: " + output)
+		return newDirSink(output, prune)
+	}
+}
+
+// Whether the output is an archive or the stream on stdout instead of a directory
+func isArchiveOutput(output string) bool {
+	return output == "-" || strings.HasSuffix(output, ".zip") || strings.HasSuffix(output, ".tar.gz") || strings.HasSuffix(output, ".tgz")
+}
+
+// Create the file an archive is written to, including its parent directories
+func createOutputFile(fileName string) *os.File {
+	if dir := filepath.Dir(fileName); dir != "." {
+		if err := os.MkdirAll(dir, dirPermissions); err != nil {
+			log.Fatal("Cannot create output directory: ", err)
+		}
+	}
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePermissions)
+	if err != nil {
+		log.Fatal("Cannot create output file: ", err)
+	}
+	return file
+}
+
+// Build the name of a generated file from the file name pattern
+func outputFileName(pattern string, dataset string, layer string, format string) string {
+	replacer := strings.NewReplacer(
+		"{dataset}", safePathElement(dataset),
+		"{layer}", safePathElement(layer),
+		"{format}", safePathElement(format),
+	)
+	name := path.Clean("/" + replacer.Replace(pattern))
+	return strings.TrimPrefix(name, "/")
+}
+
+// Make sure a value can't introduce extra directories in a file name
+func safePathElement(value string) string {
+	value = strings.NewReplacer("/", "_", "\\", "_").Replace(value)
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
+}
+
+// Name of the dataset, derived from the Geopackage location
+func datasetName(gpkgURLParam *string, gpkgPathParam *string) string {
+	location := *gpkgPathParam
+	if *gpkgURLParam != "" {
+		location = *gpkgURLParam
+		if i := strings.IndexAny(location, "?#"); i >= 0 {
+			location = location[:i]
+		}
+	}
+	base := path.Base(filepath.ToSlash(location))
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// Writes generated files into a directory, replacing existing files atomically
+type dirSink struct {
+	dir     string
+	prune   bool
+	written []string
+}
+
+func newDirSink(dir string, prune bool) *dirSink {
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		log.Fatal("Cannot create output directory: ", err)
+	}
+	lockOutputDir(dir)
+	return &dirSink{dir: dir, prune: prune}
+}
+
+func (s *dirSink) write(name string, content []byte) {
+	fileName := filepath.Join(s.dir, filepath.FromSlash(name))
+	writeFileAtomic(fileName, content)
+	s.written = append(s.written, name)
+	log.Println("Written file: " + fileName)
+}
+
+func (s *dirSink) close() {
+	previous := readManifest(s.dir)
+	if s.prune {
+		pruneStaleFiles(s.dir, previous, s.written)
+	} else {
+		s.written = mergeManifest(previous, s.written)
+	}
+	writeManifest(s.dir, s.written)
+	unlockOutputDir(s.dir)
+}
+
+// Write a file through a temporary file and a rename, so readers never see a half written file
+func writeFileAtomic(fileName string, content []byte) {
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		log.Fatal("Cannot create output directory: ", err)
+	}
+	tmpFile, err := ioutil.TempFile(dir, "."+filepath.Base(fileName)+"-*.tmp")
+	if err != nil {
+		log.Fatal("Cannot create temporary file: ", err)
+	}
+	_, err = tmpFile.Write(content)
+	if err == nil {
+		err = tmpFile.Chmod(filePermissions)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), fileName)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		log.Fatal("Cannot create file: ", err)
+	}
+}
+
+// Writes generated files into a zip archive
+type zipSink struct {
+	file   *os.File
+	writer *zip.Writer
+}
+
+func newZipSink(file *os.File) *zipSink {
+	return &zipSink{file: file, writer: zip.NewWriter(file)}
+}
+
+func (s *zipSink) write(name string, content []byte) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}
+	header.SetMode(filePermissions)
+	entry, err := s.writer.CreateHeader(header)
+	if err != nil {
+		log.Fatal("Cannot add file to zip archive: ", err)
+	}
+	if _, err = entry.Write(content); err != nil {
+		log.Fatal("Cannot add file to zip archive: ", err)
+	}
+	log.Println("Added to zip archive: " + name)
+}
+
+func (s *zipSink) close() {
+	if err := s.writer.Close(); err != nil {
+		log.Fatal("Cannot finish zip archive: ", err)
+	}
+	if err := s.file.Close(); err != nil {
+		log.Fatal("Cannot close zip archive: ", err)
+	}
+}
+
+// Writes generated files into a gzipped tar archive
+type tarGzSink struct {
+	closer io.Closer
+	gzip   *gzip.Writer
+	tar    *tar.Writer
+}
+
+func newTarGzSink(writer io.Writer, closer io.Closer) *tarGzSink {
+	gzipWriter := gzip.NewWriter(writer)
+	return &tarGzSink{closer: closer, gzip: gzipWriter, tar: tar.NewWriter(gzipWriter)}
+}
+
+func (s *tarGzSink) write(name string, content []byte) {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     filePermissions,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := s.tar.WriteHeader(header); err != nil {
+		log.Fatal("Cannot add file to tar archive: ", err)
+	}
+	if _, err := s.tar.Write(content); err != nil {
+		log.Fatal("Cannot add file to tar archive: ", err)
+	}
+	log.Println("Added to tar archive: " + name)
+}
+
+func (s *tarGzSink) close() {
+	if err := s.tar.Close(); err != nil {
+		log.Fatal("Cannot finish tar archive: ", err)
+	}
+	if err := s.gzip.Close(); err != nil {
+		log.Fatal("Cannot finish tar archive: ", err)
+	}
+	if s.closer != nil {
+		if err := s.closer.Close(); err != nil {
+			log.Fatal("Cannot close tar archive: ", err)
+		}
+	}
+}
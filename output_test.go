@@ -0,0 +1,69 @@
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_outputFileName(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		layer    string
+		expected string
+	}{
+		{defaultFileNamePattern, "testLayer", "testLayer.html"},
+		{"{dataset}/{layer}.{format}", "testLayer", "afvalwater/testLayer.html"},
+		{"{layer}.{format}", "../../etc/passwd", ".._.._etc_passwd.html"},
+		{"../{layer}.{format}", "testLayer", "testLayer.html"},
+	}
+	for _, test := range tests {
+		result := outputFileName(test.pattern, "afvalwater", test.layer, "html")
+		if result != test.expected {
+			t.Errorf("Pattern %s gave %s, expected %s.", test.pattern, result, test.expected)
+		}
+	}
+}
+
+func Test_datasetName(t *testing.T) {
+	url := "https://example.com/geopackages/afvalwater.gpkg?version=2"
+	empty := ""
+	if name := datasetName(&url, &empty); name != "afvalwater" {
+		t.Errorf("Dataset name for URL was %s.", name)
+	}
+	path := "./data/afvalwater.gpkg"
+	if name := datasetName(&empty, &path); name != "afvalwater" {
+		t.Errorf("Dataset name for path was %s.", name)
+	}
+}
+
+func Test_isArchiveOutput(t *testing.T) {
+	tests := map[string]bool{"-": true, "out.zip": true, "out.tar.gz": true, "out.tgz": true, "./output": false, "templates.d": false}
+	for output, expected := range tests {
+		if result := isArchiveOutput(output); result != expected {
+			t.Errorf("isArchiveOutput(%q) was %v, expected %v", output, result, expected)
+		}
+	}
+}
+
+func Test_zipSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpkg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "templates.zip")
+	sink := openOutputSink(archive, false)
+	sink.write("afvalwater/testLayer.html", []byte("<html></html>"))
+	sink.close()
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if len(reader.File) != 1 || reader.File[0].Name != "afvalwater/testLayer.html" {
+		t.Errorf("Unexpected zip content: %v", reader.File)
+	}
+}
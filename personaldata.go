@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const personalDataSampleSize = 100
+
+// Column names, and parts of names, that point to personal data
+var personalDataNames = map[string]bool{
+	"bsn": true, "burgerservicenummer": true, "sofinummer": true,
+	"email": true, "emailadres": true, "mail": true, "mailadres": true,
+	"telefoon": true, "telefoonnummer": true, "telnr": true, "phone": true, "mobiel": true, "mobile": true, "gsm": true, "fax": true,
+	"iban": true, "rekeningnummer": true, "bankrekening": true, "bankrekeningnummer": true,
+	"geboortedatum": true, "birthdate": true, "voornaam": true, "voornamen": true, "achternaam": true, "voorletters": true,
+	"firstname": true, "lastname": true, "surname": true, "paspoortnummer": true, "passport": true,
+}
+
+var nameSeparatorRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+var phoneValueRegexp = regexp.MustCompile(`^((\+|00)[1-9][0-9]{7,14}|0[1-9][0-9]{8})$`)
+var ibanValueRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+// Leave out the columns of a layer that hold personal data, found by name or from a sample of the values.
+// Columns on the allowlist of the configuration file are kept, in strict mode personal data fails the run.
+func applyPersonalDataPolicy(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	if !options.detectPII {
+		return
+	}
+	var columns []templateColumn
+	var found []string
+	for _, column := range layer.Columns {
+		if !column.hasAttributeValue() || options.config.allowsPersonalData(layer.Name, column.Name) {
+			columns = append(columns, column)
+			continue
+		}
+		reason := personalDataName(column.Name)
+		if reason == "" && (isTextType(column.Type) || strings.Contains(strings.ToUpper(column.Type), "INT")) {
+			reason = detectPersonalData(layer.Name, column.Name, geopackage)
+		}
+		if reason == "" {
+			columns = append(columns, column)
+			continue
+		}
+		log.Printf("Column %s of layer %s looks like personal data (%s) and is left out, add it to allowPersonalData in the config to publish it", column.Name, layer.Name, reason)
+		found = append(found, column.Name)
+	}
+	if options.strict && len(found) > 0 {
+		log.Fatalf("Error: layer %s has columns with personal data: %s", layer.Name, strings.Join(found, ", "))
+	}
+	layer.Columns = columns
+	layer.PersonalData = append(layer.PersonalData, found...)
+}
+
+// Reason a column name points to personal data, empty when it doesn't
+func personalDataName(name string) string {
+	lower := strings.ToLower(name)
+	if personalDataNames[nameSeparatorRegexp.ReplaceAllString(lower, "")] {
+		return "name"
+	}
+	for _, part := range nameSeparatorRegexp.Split(lower, -1) {
+		if personalDataNames[part] {
+			return "name"
+		}
+	}
+	return ""
+}
+
+// Detect BSNs, email addresses, phone numbers and IBANs from a sample of the values of a column.
+// More than half of the values has to match, personal data is rather left out once too often.
+func detectPersonalData(layer string, column string, geopackage *sql.DB) string {
+	rows, err := geopackage.Query("SELECT "+quoteIdentifier(column)+" FROM "+quoteIdentifier(layer)+
+		" WHERE "+quoteIdentifier(column)+" IS NOT NULL AND "+quoteIdentifier(column)+" <> '' LIMIT ?", personalDataSampleSize)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	matches := make(map[string]int)
+	count := 0
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		count++
+		value = strings.TrimSpace(value)
+		switch {
+		case isBSN(value):
+			matches["BSN values"]++
+		case emailValueRegexp.MatchString(value):
+			matches["email addresses"]++
+		case isPhoneNumber(value):
+			matches["phone numbers"]++
+		case isIBAN(value):
+			matches["IBAN values"]++
+		}
+	}
+	for reason, matched := range matches {
+		if matched*2 > count {
+			return reason
+		}
+	}
+	return ""
+}
+
+// Whether a value is a BSN: nine digits, or eight with the leading zero left out, that pass the eleven test
+func isBSN(value string) bool {
+	if len(value) == 8 {
+		value = "0" + value
+	}
+	if len(value) != 9 || strings.Trim(value, "0") == "" {
+		return false
+	}
+	sum := 0
+	for i, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+		weight := 9 - i
+		if i == 8 {
+			weight = -1
+		}
+		sum += weight * int(c-'0')
+	}
+	return sum%11 == 0
+}
+
+// Whether a value is a Dutch or international phone number, separators are ignored
+func isPhoneNumber(value string) bool {
+	return phoneValueRegexp.MatchString(strings.NewReplacer("(0)", "", " ", "", "-", "", "(", "", ")", "", ".", "").Replace(value))
+}
+
+// Whether a value is an IBAN with a valid check number
+func isIBAN(value string) bool {
+	value = strings.ToUpper(strings.Replace(value, " ", "", -1))
+	if !ibanValueRegexp.MatchString(value) {
+		return false
+	}
+	var digits strings.Builder
+	for _, c := range value[4:] + value[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(c - 'A' + 10)))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	number, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
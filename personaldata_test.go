@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_personalDataValues(t *testing.T) {
+	tests := []struct {
+		value    string
+		check    func(string) bool
+		expected bool
+	}{
+		{"111222333", isBSN, true},
+		{"11222333", isBSN, false},
+		{"111222334", isBSN, false},
+		{"000000000", isBSN, false},
+		{"06-12345678", isPhoneNumber, true},
+		{"+31 (0)20 1234567", isPhoneNumber, true},
+		{"+31 20 1234567", isPhoneNumber, true},
+		{"2020-01-05", isPhoneNumber, false},
+		{"NL91 ABNA 0417 1643 00", isIBAN, true},
+		{"NL92ABNA0417164300", isIBAN, false},
+	}
+	for _, test := range tests {
+		if test.check(test.value) != test.expected {
+			t.Errorf("Check of %s was %t, expected %t", test.value, !test.expected, test.expected)
+		}
+	}
+}
+
+func Test_personalDataName(t *testing.T) {
+	for name, expected := range map[string]bool{"bsn": true, "Contact_Email": true, "e-mail": true, "telefoon_nummer": true, "naam": false, "email_verzonden_op": true, "aantal": false} {
+		if (personalDataName(name) != "") != expected {
+			t.Errorf("Name %s was detected as personal data: %t, expected %t", name, !expected, expected)
+		}
+	}
+}
+
+func Test_applyPersonalDataPolicy(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN telefoon TEXT",
+		"ALTER TABLE putten ADD COLUMN nummer INTEGER",
+		"ALTER TABLE putten ADD COLUMN contact TEXT",
+		"ALTER TABLE putten ADD COLUMN beheerder TEXT",
+		"UPDATE putten SET nummer = 111222333, contact = 'put' || fid || '@example.com', beheerder = 'beheer@example.com'",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := &templateConfig{AllowPersonalData: []string{"putten.beheerder"}}
+	layer := buildTemplateLayer("putten", geopackage, []string{"geom"}, &templateOptions{detectPII: true, config: config})
+	var names []string
+	for _, column := range layer.Columns {
+		names = append(names, column.Name)
+	}
+	if strings.Join(names, ",") != "fid,naam,diepte,bouwdatum,actief,beheerder" {
+		t.Errorf("Unexpected columns: %v", names)
+	}
+	if strings.Join(layer.PersonalData, ",") != "telefoon,nummer,contact" {
+		t.Errorf("Unexpected personal data: %v", layer.PersonalData)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dutch government abbreviations in column names, acronyms map to their capitals
+var defaultAbbreviations = map[string]string{
+	"adr": "adres", "bwj": "bouwjaar", "dat": "datum", "gem": "gemeente", "hnr": "huisnummer", "hlt": "huisletter",
+	"ident": "identificatie", "nr": "nummer", "omschr": "omschrijving", "oorspr": "oorspronkelijk", "opp": "oppervlakte",
+	"pc": "postcode", "prov": "provincie", "str": "straat", "toev": "toevoeging", "ws": "waterschap", "wpl": "woonplaats",
+	"vbo": "verblijfsobject", "pnd": "pand", "gebr": "gebruik", "gebrdoel": "gebruiksdoel", "max": "maximum", "min": "minimum",
+	"cat": "categorie", "ind": "indicatie", "reg": "registratie", "geb": "gebied", "vlg": "volgnummer",
+	"id": "ID", "bag": "BAG", "bgt": "BGT", "brk": "BRK", "brt": "BRT", "bro": "BRO", "imgeo": "IMGeo", "kvk": "KvK",
+	"cbs": "CBS", "nap": "NAP", "rd": "RD", "nen": "NEN", "wkt": "WKT", "url": "URL", "pdok": "PDOK", "uuid": "UUID",
+}
+
+// Units for the last part of a column name, like the m2 of opp_m2
+var headerUnits = map[string]string{
+	"m": "m", "m2": "m²", "m3": "m³", "km": "km", "km2": "km²", "cm": "cm", "mm": "mm", "ha": "ha",
+	"kg": "kg", "pct": "%", "perc": "%", "procent": "%",
+}
+
+// Give columns without an alias a readable header made from their name
+func applyPrettyHeaders(layer *templateLayer, options *templateOptions) {
+	if !options.prettyHeaders {
+		return
+	}
+	abbreviations := make(map[string]string)
+	for abbreviation, word := range defaultAbbreviations {
+		abbreviations[abbreviation] = word
+	}
+	if options.config != nil {
+		for abbreviation, word := range options.config.Abbreviations {
+			abbreviations[strings.ToLower(abbreviation)] = word
+		}
+	}
+	for i, column := range layer.Columns {
+		if column.hasAttributeValue() && column.Header == column.Name {
+			layer.Columns[i].Header = prettyHeader(column.Name, column.Unit == "", abbreviations)
+		}
+	}
+}
+
+// Readable header for a column name: words split on separators and camelCase, abbreviations expanded,
+// a capital at the start and the unit of a suffix between brackets
+func prettyHeader(name string, withUnit bool, abbreviations map[string]string) string {
+	words := splitColumnName(name)
+	if len(words) == 0 {
+		return name
+	}
+	unit := ""
+	if last := words[len(words)-1]; withUnit && len(words) > 1 && headerUnits[last] != "" {
+		unit = headerUnits[last]
+		words = words[:len(words)-1]
+	}
+	for i, word := range words {
+		if expanded, ok := abbreviations[word]; ok {
+			words[i] = expanded
+		}
+	}
+	header := strings.Join(words, " ")
+	first, size := utf8.DecodeRuneInString(header)
+	header = string(unicode.ToUpper(first)) + header[size:]
+	if unit != "" {
+		header += " (" + unit + ")"
+	}
+	return header
+}
+
+// Split a column name in lower case words on separators and camelCase, letters and digits stay together like in m2
+func splitColumnName(name string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, strings.ToLower(string(word)))
+				word = nil
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			previous := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextLower) {
+				words = append(words, strings.ToLower(string(word)))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, strings.ToLower(string(word)))
+	}
+	return words
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func Test_prettyHeader(t *testing.T) {
+	tests := map[string]string{
+		"bouwjaar_oorspr":     "Bouwjaar oorspronkelijk",
+		"OPP_M2":              "Oppervlakte (m²)",
+		"aantalWoningen":      "Aantal woningen",
+		"BAGId":               "BAG ID",
+		"hoogte_m":            "Hoogte (m)",
+		"m":                   "M",
+		"perc_groen":          "Perc groen",
+		"inhoud-m3":           "Inhoud (m³)",
+		"straatnaam":          "Straatnaam",
+		"ws_code":             "Waterschap code",
+		"Overstromingsdiepte": "Overstromingsdiepte",
+	}
+	for name, expected := range tests {
+		if header := prettyHeader(name, true, defaultAbbreviations); header != expected {
+			t.Errorf("Header of %s was %s, expected %s", name, header, expected)
+		}
+	}
+	if header := prettyHeader("opp_m2", false, defaultAbbreviations); header != "Oppervlakte m2" {
+		t.Errorf("Header without unit was %s, expected Oppervlakte m2", header)
+	}
+}
+
+func Test_applyPrettyHeaders(t *testing.T) {
+	layer := templateLayer{Name: "panden", Columns: []templateColumn{
+		{Name: "bwj_oorspr", Header: "bwj_oorspr"},
+		{Name: "opp_m2", Header: "Oppervlakte"},
+		{Name: "inh", Header: "inh"},
+	}}
+	config := &templateConfig{Abbreviations: map[string]string{"INH": "inhoud", "bwj": "bouwjaar"}}
+	applyPrettyHeaders(&layer, &templateOptions{prettyHeaders: true, config: config})
+	for i, expected := range []string{"Bouwjaar oorspronkelijk", "Oppervlakte", "Inhoud"} {
+		if layer.Columns[i].Header != expected {
+			t.Errorf("Header of %s was %s, expected %s", layer.Columns[i].Name, layer.Columns[i].Header, expected)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"flag"
+	"html/template"
+	"log"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultSampleSize = 5
+
+// Render the generated templates with sample features from the Geopackage into static preview pages
+func previewCommand(args []string) {
+	startTime := time.Now()
+	flags := flag.NewFlagSet("preview", flag.ExitOnError)
+	gpkgURLParam := flags.String("gpkg-url", "", "URL pointing to a geopackage (https://example.com/geopackage.gpkg)")
+	gpkgPathParam := flags.String("gpkg-path", "", "Path pointing to a geopackage (./geopackage.gpkg)")
+	outputParam := flags.String("output", "preview", "Output directory, .zip or .tar.gz archive, or - for a tar.gz stream on stdout")
+	samplesParam := flags.Int("samples", defaultSampleSize, "Number of sample features rendered per layer")
+	options := registerTemplateFlags(flags)
+	flags.Parse(args)
+	checkLocationParameters(gpkgURLParam, gpkgPathParam)
+	options.check()
+	gpkgFile := getGpkgFile(gpkgURLParam, gpkgPathParam)
+	geopackage := openGeopackage(gpkgFile)
+	defer gpkgFile.Close()
+	defer geopackage.Close()
+	geomColumns := getGeometryColumnsFromGeopackage(geopackage)
+	layers := getLayersFromGeopackage(geopackage)
+	sink := newMemorySink()
+	var pages []previewPage
+	for _, layer := range layers {
+		model := buildTemplateLayer(layer, geopackage, geomColumns, options)
+		if languages := options.languages(); languages != nil {
+			model = translateLayer(model, languages[0], options.config)
+		}
+		htmlBuffer := generateHTML(model)
+		features := getSampleFeatures(model, geopackage, *samplesParam)
+		addRelatedRecords(features, model, geopackage)
+		log.Printf("Render preview for layer %s with %d features", layer, len(features))
+		fileName := outputFileName(defaultFileNamePattern, "", layer, "html")
+		sink.write(fileName, []byte(renderMapserverTemplate(htmlBuffer.String(), layer, features)))
+		pages = append(pages, previewPage{Layer: layer, File: fileName, Features: len(features)})
+	}
+	sink.write("index.html", generatePreviewIndex(datasetName(gpkgURLParam, gpkgPathParam), pages).Bytes())
+	sink.copyTo(openOutputSink(*outputParam, false))
+	cleanup(gpkgFile, gpkgURLParam)
+	programFinishedSuccesfully(startTime)
+}
+
+// Check if parameters of a command are provided, like checkParameters does for the flags of the program
+func checkLocationParameters(gpkgURLParam *string, gpkgPathParam *string) {
+	if *gpkgURLParam == "" && *gpkgPathParam == "" {
+		log.Fatal("Error: gpkg-url or gpkg-path is required. Run with -h for help.")
+	} else if *gpkgURLParam != "" && *gpkgPathParam != "" {
+		log.Fatal("Error: either gpkg-url or gpkg-path is required. Run with -h for help.")
+	}
+}
+
+// Read sample features from a layer, with values formatted as MapServer passes them to a template
+func getSampleFeatures(layer templateLayer, geopackage *sql.DB, limit int) []templateFeature {
+	rows, errDb := geopackage.Query(layer.selectSQL()+" LIMIT ?", limit)
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	columns, values, errDb := readRows(rows)
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	var features []templateFeature
+	for _, row := range values {
+		features = append(features, newTemplateFeature(columns, row))
+	}
+	return features
+}
+
+// Create a template feature from a row, the first geometry found becomes the geometry of the feature
+func newTemplateFeature(columns []string, row []interface{}) templateFeature {
+	feature := templateFeature{Values: make(map[string]string)}
+	for i, column := range columns {
+		feature.Values[column] = templateValue(row[i])
+		if blob, ok := row[i].([]byte); ok && feature.Geometry == nil {
+			if geom, err := decodeGeoPackageBinary(blob); err == nil && !geom.Empty {
+				feature.Geometry = &geom
+			}
+		}
+	}
+	if feature.Geometry != nil {
+		addGeometryMeasures(feature.Values, *feature.Geometry)
+	}
+	return feature
+}
+
+// Read all rows of a query result
+func readRows(rows *sql.Rows) ([]string, [][]interface{}, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var result [][]interface{}
+	for rows.Next() {
+		values, err := scanRow(rows, len(columns))
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, values)
+	}
+	return columns, result, rows.Err()
+}
+
+// Read the values of the current row of a query result
+func scanRow(rows *sql.Rows, columns int) ([]interface{}, error) {
+	values := make([]interface{}, columns)
+	pointers := make([]interface{}, columns)
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	return values, rows.Scan(pointers...)
+}
+
+// Format a database value as text, NULL and binary values become empty
+func templateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', 15, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02T15:04:05")
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return ""
+	case string:
+		return v
+	}
+	return ""
+}
+
+// Preview page of a layer, listed in the index page
+type previewPage struct {
+	Layer    string
+	File     string
+	Features int
+}
+
+// Generate the index page linking to the preview pages
+func generatePreviewIndex(dataset string, pages []previewPage) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	indexTemplate, err := template.New("index").Parse(htmlPreviewIndex)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = indexTemplate.Execute(buf, map[string]interface{}{
+		"dataset": dataset,
+		"pages":   pages,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return buf
+}
+
+const htmlPreviewIndex = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8">
+		<title>Preview {{.dataset}}</title>
+	</head>
+	<body>
+		<h1>Preview {{.dataset}}</h1>
+		<ul>
+{{- range .pages}}
+			<li><a href="{{.File}}">{{.Layer}}</a> ({{.Features}} features)</li>
+{{- end}}
+		</ul>
+	</body>
+</html>
+`
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_getSampleFeatures(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	features := getSampleFeatures(newTemplateLayer("putten", nil, nil), geopackage, 1)
+	if len(features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(features))
+	}
+	expected := map[string]string{"fid": "1", "geom": "", "naam": "Put 1", "diepte": "2.5", "bouwdatum": "2020-01-05", "actief": "1", "geom_area": "0", "geom_length": "0"}
+	if !reflect.DeepEqual(features[0].Values, expected) {
+		t.Errorf("Unexpected feature: %v", features[0].Values)
+	}
+	if features[0].Geometry == nil || features[0].Geometry.Coordinates[0] != (point{150000, 450000}) {
+		t.Errorf("Unexpected geometry: %v", features[0].Geometry)
+	}
+}
+
+func Test_generatePreviewIndex(t *testing.T) {
+	index := generatePreviewIndex("afvalwater", []previewPage{{Layer: "putten", File: "putten.html", Features: 2}}).String()
+	if !strings.Contains(index, `<li><a href="putten.html">putten</a> (2 features)</li>`) {
+		t.Errorf("Unexpected index:\n%s", index)
+	}
+}
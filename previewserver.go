@@ -0,0 +1,321 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxServedFeatures = 10000
+const defaultOpenLayers = "https://cdn.jsdelivr.net/npm/ol@v9.2.4"
+const openLayersPath = "/openlayers/"
+
+// Feature layer served by the preview server
+type serverLayer struct {
+	Name           string
+	SrsID          int64
+	geometryColumn string
+	rtree          string
+	template       string
+	model          templateLayer
+}
+
+// Emulates MapServer GetFeatureInfo for the layers of a Geopackage
+type previewServer struct {
+	geopackage  *sql.DB
+	layers      map[string]serverLayer
+	maxFeatures int
+}
+
+// Serve a map of the Geopackage that shows the rendered templates for the features clicked on
+func previewServerCommand(args []string) {
+	flags := flag.NewFlagSet("preview-server", flag.ExitOnError)
+	gpkgURLParam := flags.String("gpkg-url", "", "URL pointing to a geopackage (https://example.com/geopackage.gpkg)")
+	gpkgPathParam := flags.String("gpkg-path", "", "Path pointing to a geopackage (./geopackage.gpkg)")
+	listenParam := flags.String("listen", "localhost:8080", "Address the preview server listens on")
+	toleranceParam := flags.Int("tolerance", 5, "Tolerance in pixels around the clicked point")
+	maxFeaturesParam := flags.Int("max-features", 10, "Maximum number of features rendered for a click")
+	openLayersParam := flags.String("openlayers", defaultOpenLayers, "URL or local directory of the OpenLayers package with ol.css and dist/ol.js, a directory is served for use offline")
+	options := registerTemplateFlags(flags)
+	flags.Parse(args)
+	checkLocationParameters(gpkgURLParam, gpkgPathParam)
+	options.check()
+	gpkgFile := getGpkgFile(gpkgURLParam, gpkgPathParam)
+	geopackage := openGeopackage(gpkgFile)
+	defer gpkgFile.Close()
+	defer geopackage.Close()
+	defer cleanup(gpkgFile, gpkgURLParam)
+	server := &previewServer{
+		geopackage:  geopackage,
+		layers:      getServerLayers(geopackage, options),
+		maxFeatures: *maxFeaturesParam,
+	}
+	mux := http.NewServeMux()
+	openLayers := *openLayersParam
+	if info, err := os.Stat(openLayers); err == nil && info.IsDir() {
+		mux.Handle(openLayersPath, http.StripPrefix(openLayersPath, http.FileServer(http.Dir(openLayers))))
+		openLayers = strings.TrimSuffix(openLayersPath, "/")
+	}
+	mux.HandleFunc("/", server.handleIndex(*toleranceParam, openLayers))
+	mux.HandleFunc("/features", server.handleFeatures)
+	mux.HandleFunc("/featureinfo", server.handleFeatureInfo)
+	log.Printf("Preview server listening on http://%s/", *listenParam)
+	log.Fatal(http.ListenAndServe(*listenParam, mux))
+}
+
+// Collect the feature layers with their generated template
+func getServerLayers(geopackage *sql.DB, options *templateOptions) map[string]serverLayer {
+	geomColumns := getGeometryColumnsFromGeopackage(geopackage)
+	geometryColumnPerLayer := getGeometryColumnPerLayer(geopackage)
+	metadata := getLayerMetadataFromGeopackage(geopackage)
+	layers := make(map[string]serverLayer)
+	for _, layer := range getLayersFromGeopackage(geopackage) {
+		geometryColumn, ok := geometryColumnPerLayer[layer]
+		if !ok {
+			continue
+		}
+		model := buildTemplateLayer(layer, geopackage, geomColumns, options)
+		if languages := options.languages(); languages != nil {
+			model = translateLayer(model, languages[0], options.config)
+		}
+		layers[layer] = serverLayer{
+			Name:           layer,
+			SrsID:          metadata[layer].SrsID,
+			geometryColumn: geometryColumn.Name,
+			rtree:          getRTreeForLayer(layer, geometryColumn.Name, geopackage),
+			template:       generateHTML(model).String(),
+			model:          model,
+		}
+	}
+	return layers
+}
+
+// Find the R-tree spatial index of a layer, empty when there is none
+func getRTreeForLayer(layer string, geometryColumn string, geopackage *sql.DB) string {
+	rtree := "rtree_" + layer + "_" + geometryColumn
+	var name string
+	err := geopackage.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", rtree).Scan(&name)
+	if err == sql.ErrNoRows {
+		log.Printf("No spatial index found for layer %s, queries will scan all features", layer)
+		return ""
+	}
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	return name
+}
+
+// Find the features within a tolerance of a point, using the R-tree index when there is one, its ids are row IDs.
+// Rows are read one at a time and reading stops at the maximum number of features.
+func (s *previewServer) queryFeaturesAtPoint(layer serverLayer, p point, tolerance float64) ([]templateFeature, error) {
+	query := layer.model.selectSQL()
+	var args []interface{}
+	if layer.rtree != "" {
+		query += " WHERE rowid IN (SELECT id FROM " + quoteIdentifier(layer.rtree) +
+			" WHERE minx <= ? AND maxx >= ? AND miny <= ? AND maxy >= ?)"
+		args = []interface{}{p.X + tolerance, p.X - tolerance, p.Y + tolerance, p.Y - tolerance}
+	} else {
+		ids, err := s.featureIDsNearPoint(layer, p, tolerance)
+		if err != nil || len(ids) == 0 {
+			return nil, err
+		}
+		query += " WHERE rowid IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		args = ids
+	}
+	rows, err := s.geopackage.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var features []templateFeature
+	for len(features) < s.maxFeatures && rows.Next() {
+		row, err := scanRow(rows, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		feature := newTemplateFeature(columns, row)
+		if feature.Geometry != nil && feature.Geometry.distanceTo(p) <= tolerance {
+			features = append(features, feature)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	addRelatedRecords(features, layer.model, s.geopackage)
+	return features, nil
+}
+
+// Row IDs of the features within a tolerance of a point for layers without a spatial index.
+// Only the geometries are read, and the envelope in their header is checked before a geometry is decoded.
+func (s *previewServer) featureIDsNearPoint(layer serverLayer, p point, tolerance float64) ([]interface{}, error) {
+	rows, err := s.geopackage.Query("SELECT rowid, " + quoteIdentifier(layer.geometryColumn) + " FROM " + quoteIdentifier(layer.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []interface{}
+	for len(ids) < s.maxFeatures && rows.Next() {
+		var id int64
+		var blob []byte
+		if err = rows.Scan(&id, &blob); err != nil {
+			return nil, err
+		}
+		if env, ok := headerEnvelope(blob); ok && !env.near(p, tolerance) {
+			continue
+		}
+		geom, err := decodeGeoPackageBinary(blob)
+		if err != nil || geom.Empty || geom.distanceTo(p) > tolerance {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Render the template of a layer for the features at the requested point
+func (s *previewServer) handleFeatureInfo(w http.ResponseWriter, r *http.Request) {
+	layer, ok := s.layers[r.FormValue("layer")]
+	if !ok {
+		http.Error(w, "unknown layer", http.StatusBadRequest)
+		return
+	}
+	x, errX := strconv.ParseFloat(r.FormValue("x"), 64)
+	y, errY := strconv.ParseFloat(r.FormValue("y"), 64)
+	tolerance, errTolerance := strconv.ParseFloat(r.FormValue("tolerance"), 64)
+	if errX != nil || errY != nil || errTolerance != nil {
+		http.Error(w, "x, y and tolerance must be numbers", http.StatusBadRequest)
+		return
+	}
+	features, err := s.queryFeaturesAtPoint(layer, point{x, y}, tolerance)
+	if err != nil {
+		log.Print("Error with querying Geopackage: ", err)
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("GetFeatureInfo %s at %f,%f: %d features", layer.Name, x, y, len(features))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(renderMapserverTemplate(layer.template, layer.Name, features)))
+}
+
+// Serve the geometries of a layer as GeoJSON, in the coordinate system of the Geopackage
+func (s *previewServer) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	layer, ok := s.layers[r.FormValue("layer")]
+	if !ok {
+		http.Error(w, "unknown layer", http.StatusBadRequest)
+		return
+	}
+	rows, err := s.geopackage.Query("SELECT "+quoteIdentifier(layer.geometryColumn)+" FROM "+quoteIdentifier(layer.Name)+" LIMIT ?", maxServedFeatures)
+	if err != nil {
+		log.Print("Error with querying Geopackage: ", err)
+		http.Error(w, "query failed", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	var features []interface{}
+	for rows.Next() {
+		var blob []byte
+		if err = rows.Scan(&blob); err != nil {
+			continue
+		}
+		geom, err := decodeGeoPackageBinary(blob)
+		if err != nil || geom.Empty {
+			continue
+		}
+		features = append(features, map[string]interface{}{"type": "Feature", "geometry": geom.geoJSON(), "properties": nil})
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"type": "FeatureCollection", "features": features})
+}
+
+// Serve the map page
+func (s *previewServer) handleIndex(tolerance int, openLayers string) http.HandlerFunc {
+	pageTemplate := template.Must(template.New("page").Parse(htmlPreviewServer))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		var layers []serverLayer
+		for _, layer := range s.layers {
+			layers = append(layers, layer)
+		}
+		sort.Slice(layers, func(i, j int) bool { return layers[i].Name < layers[j].Name })
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := pageTemplate.Execute(w, map[string]interface{}{"layers": layers, "tolerance": tolerance, "openlayers": strings.TrimSuffix(openLayers, "/")})
+		if err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+const htmlPreviewServer = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8">
+		<title>GetFeatureInfo preview</title>
+		<link rel="stylesheet" href="{{.openlayers}}/ol.css">
+		<script src="{{.openlayers}}/dist/ol.js"></script>
+		<style>
+			body { margin: 0; display: flex; height: 100vh; font-family: sans-serif; }
+			#map { flex: 2; }
+			#info { flex: 1; display: flex; flex-direction: column; border-left: 1px solid #ddd; }
+			#info select { margin: .5em; }
+			#info iframe { flex: 1; border: 0; }
+		</style>
+	</head>
+	<body>
+		<div id="map"></div>
+		<div id="info">
+			<select id="layer">
+{{- range .layers}}
+				<option value="{{.Name}}" data-srs="{{.SrsID}}">{{.Name}}</option>
+{{- end}}
+			</select>
+			<iframe id="result"></iframe>
+		</div>
+		<script>
+			const tolerance = {{.tolerance}};
+			const select = document.getElementById('layer');
+			const result = document.getElementById('result');
+			let map;
+			function showLayer() {
+				const option = select.options[select.selectedIndex];
+				const code = 'EPSG:' + option.dataset.srs;
+				const projection = ol.proj.get(code) || new ol.proj.Projection({code: code, units: 'm'});
+				const source = new ol.source.Vector({url: 'features?layer=' + encodeURIComponent(select.value), format: new ol.format.GeoJSON()});
+				if (map) {
+					map.setTarget(null);
+				}
+				map = new ol.Map({
+					target: 'map',
+					layers: [new ol.layer.Vector({source: source})],
+					view: new ol.View({projection: projection, center: [0, 0], zoom: 2})
+				});
+				source.once('featuresloadend', () => map.getView().fit(source.getExtent(), {padding: [20, 20, 20, 20]}));
+				map.on('singleclick', (event) => {
+					const params = new URLSearchParams({
+						layer: select.value,
+						x: event.coordinate[0],
+						y: event.coordinate[1],
+						tolerance: tolerance * map.getView().getResolution()
+					});
+					fetch('featureinfo?' + params).then((response) => response.text()).then((html) => result.srcdoc = html);
+				});
+			}
+			select.addEventListener('change', showLayer);
+			showLayer();
+		</script>
+	</body>
+</html>
+`
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_handleFeatureInfo(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	server := &previewServer{geopackage: geopackage, layers: getServerLayers(geopackage, &templateOptions{}), maxFeatures: 10}
+	recorder := httptest.NewRecorder()
+	server.handleFeatureInfo(recorder, httptest.NewRequest("GET", "/featureinfo?layer=putten&x=150003&y=450004&tolerance=5", nil))
+	result := recorder.Body.String()
+	if !strings.Contains(result, "<td>Put 1</td>") || strings.Contains(result, "<td>Put 2</td>") {
+		t.Errorf("Unexpected result:\n%s", result)
+	}
+	recorder = httptest.NewRecorder()
+	server.handleFeatureInfo(recorder, httptest.NewRequest("GET", "/featureinfo?layer=onbekend&x=0&y=0&tolerance=1", nil))
+	if recorder.Code != 400 {
+		t.Errorf("Unknown layer should give status 400, got %d", recorder.Code)
+	}
+}
+
+func Test_handleFeatureInfo_rtree(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"CREATE TABLE kolken (code TEXT PRIMARY KEY, geom POINT, naam TEXT)",
+		"INSERT INTO gpkg_contents VALUES ('kolken', 'features', 'Kolken', '', NULL, 150000, 450000, 150010, 450010, 28992)",
+		"INSERT INTO gpkg_geometry_columns VALUES ('kolken', 'geom', 'POINT', 28992, 0, 0)",
+		"INSERT INTO kolken (code, geom, naam) SELECT 'K' || fid, geom, 'Kolk ' || fid FROM putten",
+		"CREATE TABLE rtree_kolken_geom (id INTEGER PRIMARY KEY, minx REAL, maxx REAL, miny REAL, maxy REAL)",
+		"INSERT INTO rtree_kolken_geom SELECT rowid, 150000, 150000, 450000, 450000 FROM kolken WHERE code = 'K1'",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := &previewServer{geopackage: geopackage, layers: getServerLayers(geopackage, &templateOptions{}), maxFeatures: 10}
+	if server.layers["kolken"].rtree == "" {
+		t.Fatal("Spatial index of kolken was not found")
+	}
+	recorder := httptest.NewRecorder()
+	server.handleFeatureInfo(recorder, httptest.NewRequest("GET", "/featureinfo?layer=kolken&x=150003&y=450004&tolerance=5", nil))
+	if result := recorder.Body.String(); !strings.Contains(result, "<td>Kolk 1</td>") {
+		t.Errorf("Unexpected result:\n%s", result)
+	}
+}
+
+func Test_handleIndex(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	server := &previewServer{geopackage: geopackage, layers: getServerLayers(geopackage, &templateOptions{}), maxFeatures: 10}
+	recorder := httptest.NewRecorder()
+	server.handleIndex(5, "/openlayers/")(recorder, httptest.NewRequest("GET", "/", nil))
+	result := recorder.Body.String()
+	if !strings.Contains(result, `<script src="/openlayers/dist/ol.js">`) || !strings.Contains(result, `href="/openlayers/ol.css"`) {
+		t.Errorf("Page doesn't load OpenLayers from /openlayers:\n%s", result)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const profileSampleSize = 5
+
+// Profile of the values of a column, with the reason it is hidden when the thresholds hide it
+type columnProfile struct {
+	Column    string   `json:"column"`
+	Type      string   `json:"type"`
+	NullRatio float64  `json:"nullRatio"`
+	Distinct  int      `json:"distinct"`
+	Min       *string  `json:"min,omitempty"`
+	Max       *string  `json:"max,omitempty"`
+	Samples   []string `json:"samples,omitempty"`
+	Hidden    string   `json:"hidden,omitempty"`
+}
+
+// Profile of a layer and the columns left out of its templates
+type layerReport struct {
+	Layer        string            `json:"layer"`
+	Features     int               `json:"features"`
+	Classes      map[string]string `json:"classes,omitempty"`
+	Columns      []columnProfile   `json:"columns,omitempty"`
+	PersonalData []string          `json:"personalData,omitempty"`
+	Excluded     []string          `json:"excluded,omitempty"`
+}
+
+// Report of a run, written with -report so curators can decide which columns to show
+type runReport struct {
+	Generated time.Time     `json:"generated"`
+	Layers    []layerReport `json:"layers"`
+}
+
+// Profile the columns of a layer and hide the columns that are empty too often or have too few distinct values.
+// Columns the configuration file shows explicitly stay, and layers with a single feature only get a profile.
+func applyProfile(layer *templateLayer, geopackage *sql.DB, options *templateOptions) {
+	if !options.profile {
+		return
+	}
+	var features int
+	if err := geopackage.QueryRow("SELECT count(*) FROM " + quoteIdentifier(layer.Name)).Scan(&features); err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	layer.Features = features
+	var columns []templateColumn
+	for _, column := range layer.Columns {
+		if !column.hasAttributeValue() {
+			columns = append(columns, column)
+			continue
+		}
+		profile := profileColumn(layer.Name, column, features, geopackage)
+		if features > 1 && !options.config.shows(layer.Name, column.Name) {
+			switch {
+			case profile.Distinct == 0:
+				profile.Hidden = "always empty"
+			case profile.NullRatio >= options.profileNullRatio:
+				profile.Hidden = "mostly empty"
+			case profile.Distinct < options.profileMinDistinct:
+				profile.Hidden = "too few distinct values"
+			}
+		}
+		layer.Profile = append(layer.Profile, profile)
+		if profile.Hidden != "" {
+			log.Printf("Column %s of layer %s is left out by the profile (%s), set hidden to false in the config to show it", column.Name, layer.Name, profile.Hidden)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	layer.Columns = columns
+}
+
+// Null ratio, distinct count, minimum, maximum and a few sample values of a column, empty texts count as NULL
+func profileColumn(layer string, column templateColumn, features int, geopackage *sql.DB) columnProfile {
+	profile := columnProfile{Column: column.Name, Type: column.Type}
+	value := "NULLIF(" + quoteIdentifier(column.Name) + ", '')"
+	var filled int
+	var min, max sql.NullString
+	err := geopackage.QueryRow("SELECT count("+value+"), count(DISTINCT "+value+"), min("+value+"), max("+value+") FROM "+quoteIdentifier(layer)).
+		Scan(&filled, &profile.Distinct, &min, &max)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	if features > 0 {
+		profile.NullRatio = float64(features-filled) / float64(features)
+	}
+	if column.Type == "BLOB" {
+		return profile
+	}
+	if min.Valid {
+		profile.Min, profile.Max = &min.String, &max.String
+	}
+	rows, err := geopackage.Query("SELECT DISTINCT "+value+" FROM "+quoteIdentifier(layer)+" WHERE "+value+" IS NOT NULL LIMIT ?", profileSampleSize)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sample string
+		if err = rows.Scan(&sample); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		profile.Samples = append(profile.Samples, sample)
+	}
+	return profile
+}
+
+// Report of a layer for the run report
+func (l templateLayer) report() layerReport {
+	return layerReport{Layer: l.Name, Features: l.Features, Classes: l.Classes, Columns: l.Profile, PersonalData: l.PersonalData, Excluded: l.Excluded}
+}
+
+// Write the run report as JSON
+func writeReport(path string, layers []layerReport) {
+	content, err := json.MarshalIndent(runReport{Generated: time.Now().UTC(), Layers: layers}, "", "  ")
+	if err != nil {
+		log.Fatal("Cannot create report: ", err)
+	}
+	writeFileAtomic(path, append(content, '\n'))
+	log.Print("Written report: " + path)
+}
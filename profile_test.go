@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_applyProfile(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"ALTER TABLE putten ADD COLUMN leeg TEXT",
+		"ALTER TABLE putten ADD COLUMN beheerder TEXT DEFAULT 'Gemeente'",
+		"ALTER TABLE putten ADD COLUMN bron TEXT DEFAULT 'BGT'",
+		"UPDATE putten SET leeg = ''",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shown := false
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {Columns: map[string]columnConfig{"bron": {Hidden: &shown}}}}}
+	options := &templateOptions{profile: true, profileNullRatio: 1, profileMinDistinct: 2, config: config}
+	layer := buildTemplateLayer("putten", geopackage, []string{"geom"}, options)
+	var names []string
+	for _, column := range layer.Columns {
+		names = append(names, column.Name)
+	}
+	if strings.Join(names, ",") != "fid,naam,bouwdatum,actief,bron" {
+		t.Errorf("Unexpected columns: %v", names)
+	}
+	hidden := make(map[string]string)
+	for _, profile := range layer.Profile {
+		hidden[profile.Column] = profile.Hidden
+	}
+	if hidden["leeg"] != "always empty" || hidden["diepte"] != "too few distinct values" || hidden["beheerder"] != "too few distinct values" {
+		t.Errorf("Unexpected hidden columns: %v", hidden)
+	}
+	diepte := layer.Profile[2]
+	if diepte.Column != "diepte" || diepte.NullRatio != 0.5 || diepte.Distinct != 1 || *diepte.Min != "2.5" || len(diepte.Samples) != 1 {
+		t.Errorf("Unexpected profile: %+v", diepte)
+	}
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+	writeReport(path, []layerReport{layer.report()})
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"hidden": "always empty"`) || !strings.Contains(string(content), `"features": 2`) {
+		t.Errorf("Unexpected report:\n%s", content)
+	}
+}
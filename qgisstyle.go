@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"log"
+	"strings"
+)
+
+// QGIS uses this value in a ValueMap for NULL
+const qgisNullValue = "{2839923C-8B7D-419E-B84B-CA2FE9B80EC7}"
+
+// Parts of a QGIS style (QML) that are used for the templates
+type qgisStyle struct {
+	Fields []struct {
+		Name               string `xml:"name,attr"`
+		ConfigurationFlags string `xml:"configurationFlags,attr"`
+		EditWidget         struct {
+			Type    string       `xml:"type,attr"`
+			Options []qgisOption `xml:"config>Option"`
+		} `xml:"editWidget"`
+	} `xml:"fieldConfiguration>field"`
+	Aliases []struct {
+		Field string `xml:"field,attr"`
+		Name  string `xml:"name,attr"`
+	} `xml:"aliases>alias"`
+	Columns []struct {
+		Type string `xml:"type,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"attributetableconfig>columns>column"`
+}
+
+// Option of a QGIS widget configuration, options nest as maps and lists
+type qgisOption struct {
+	Type    string       `xml:"type,attr"`
+	Name    string       `xml:"name,attr"`
+	Value   string       `xml:"value,attr"`
+	Options []qgisOption `xml:"Option"`
+}
+
+// Read the default QGIS style of a layer from the layer_styles table, nil when there is none
+func getQGISStyleForLayer(layer string, geopackage *sql.DB) *qgisStyle {
+	var table string
+	err := geopackage.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'layer_styles'").Scan(&table)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	var qml string
+	err = geopackage.QueryRow("SELECT styleQML FROM layer_styles WHERE f_table_name = ? AND styleQML IS NOT NULL ORDER BY useAsDefault DESC, update_time DESC LIMIT 1", layer).Scan(&qml)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	style, err := parseQGISStyle(qml)
+	if err != nil {
+		log.Printf("Warning: cannot parse the QGIS style of layer %s: %v", layer, err)
+		return nil
+	}
+	log.Printf("Using the QGIS style of layer %s for headers, hidden fields, order and value maps", layer)
+	return style
+}
+
+func parseQGISStyle(qml string) (*qgisStyle, error) {
+	style := &qgisStyle{}
+	if err := xml.Unmarshal([]byte(qml), style); err != nil {
+		return nil, err
+	}
+	return style, nil
+}
+
+// Use the aliases, hidden fields, field order and value maps of a QGIS style for a layer
+func applyQGISStyle(layer *templateLayer, style *qgisStyle, geopackage *sql.DB, config *templateConfig) {
+	aliases := make(map[string]string)
+	for _, alias := range style.Aliases {
+		if alias.Name != "" {
+			aliases[alias.Field] = alias.Name
+		}
+	}
+	hidden := make(map[string]bool)
+	valueMaps := make(map[string]codeList)
+	for _, field := range style.Fields {
+		if field.EditWidget.Type == "Hidden" || strings.Contains(field.ConfigurationFlags, "HideFromWms") {
+			hidden[field.Name] = true
+		}
+		if field.EditWidget.Type == "ValueMap" {
+			list := qgisValueMap(field.EditWidget.Options)
+			list.Codes = mapserverCodes(list.Codes, "the value map of column "+field.Name+" of layer "+layer.Name)
+			valueMaps[field.Name] = list
+		}
+	}
+	var order []string
+	for _, column := range style.Columns {
+		if column.Type == "field" {
+			order = append(order, column.Name)
+		}
+	}
+	var columns []templateColumn
+	for _, column := range layer.Columns {
+		if hidden[column.Name] && !config.shows(layer.Name, column.Name) {
+			continue
+		}
+		if alias, ok := aliases[column.Name]; ok {
+			column.Header = alias
+		}
+		if list, ok := valueMaps[column.Name]; ok {
+			if geopackage != nil {
+				checkCodeListValues(layer.Name, column.Name, list, geopackage)
+			}
+			column.CodeList = &list
+		}
+		columns = append(columns, column)
+	}
+	layer.Columns = columns
+	layer.orderColumns(order)
+}
+
+// Codes and labels of a ValueMap widget, QGIS 3 stores a list of maps and older versions a single map
+func qgisValueMap(options []qgisOption) codeList {
+	var list codeList
+	var add func(options []qgisOption)
+	add = func(options []qgisOption) {
+		for _, option := range options {
+			switch {
+			case option.Type == "Map" || option.Type == "List":
+				add(option.Options)
+			case option.Value != qgisNullValue:
+				list.Codes = append(list.Codes, codeLabel{Code: option.Value, Label: option.Name})
+			}
+		}
+	}
+	for _, option := range options {
+		for _, child := range option.Options {
+			if child.Name == "map" {
+				add(child.Options)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testQML = `<!DOCTYPE qgis PUBLIC 'http://mrcc.com/qgis.dtd' 'SYSTEM'>
+<qgis version="3.28.0-Firenze" styleCategories="AllStyleCategories">
+  <fieldConfiguration>
+    <field name="fid" configurationFlags="HideFromWms|HideFromWfs">
+      <editWidget type="TextEdit"><config><Option/></config></editWidget>
+    </field>
+    <field name="naam" configurationFlags="None">
+      <editWidget type="TextEdit"><config><Option/></config></editWidget>
+    </field>
+    <field name="actief" configurationFlags="None">
+      <editWidget type="ValueMap">
+        <config>
+          <Option type="Map">
+            <Option type="List" name="map">
+              <Option type="Map"><Option type="QString" name="Ja" value="1"/></Option>
+              <Option type="Map"><Option type="QString" name="Nee" value="0"/></Option>
+              <Option type="Map"><Option type="QString" name="Onbekend" value="[x]"/></Option>
+              <Option type="Map"><Option type="QString" name="&lt;NULL&gt;" value="{2839923C-8B7D-419E-B84B-CA2FE9B80EC7}"/></Option>
+            </Option>
+          </Option>
+        </config>
+      </editWidget>
+    </field>
+    <field name="bouwdatum" configurationFlags="None">
+      <editWidget type="Hidden"><config><Option/></config></editWidget>
+    </field>
+  </fieldConfiguration>
+  <aliases>
+    <alias field="fid" index="0" name=""/>
+    <alias field="naam" index="2" name="Naam van de put"/>
+    <alias field="diepte" index="3" name="Diepte &amp; &lt;max&gt; [m]"/>
+  </aliases>
+  <attributetableconfig actionWidgetStyle="dropDown" sortExpression="" sortOrder="0">
+    <columns>
+      <column type="field" name="actief" width="-1" hidden="0"/>
+      <column type="field" name="diepte" width="-1" hidden="0"/>
+      <column type="actions" width="-1" hidden="1"/>
+      <column type="field" name="naam" width="-1" hidden="0"/>
+    </columns>
+  </attributetableconfig>
+</qgis>`
+
+func testStyleLayer(t *testing.T, config *templateConfig) templateLayer {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"CREATE TABLE layer_styles (id INTEGER PRIMARY KEY AUTOINCREMENT, f_table_catalog TEXT, f_table_schema TEXT, f_table_name TEXT, f_geometry_column TEXT, styleName TEXT, styleQML TEXT, styleSLD TEXT, useAsDefault BOOLEAN, description TEXT, owner TEXT, ui TEXT, update_time DATETIME)",
+		"INSERT INTO layer_styles (f_table_name, styleName, styleQML, useAsDefault) VALUES ('putten', 'oud', '<qgis/>', 0)",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := geopackage.Exec("INSERT INTO layer_styles (f_table_name, styleName, styleQML, useAsDefault) VALUES ('putten', 'standaard', ?, 1)", testQML); err != nil {
+		t.Fatal(err)
+	}
+	style := getQGISStyleForLayer("putten", geopackage)
+	if style == nil {
+		t.Fatal("No QGIS style found")
+	}
+	layer := newTemplateLayer("putten", []string{"fid", "geom", "naam", "diepte", "bouwdatum", "actief"}, []string{"geom"})
+	applyQGISStyle(&layer, style, geopackage, config)
+	applyColumnConfig(&layer, config)
+	return layer
+}
+
+func Test_applyQGISStyle(t *testing.T) {
+	layer := testStyleLayer(t, nil)
+	var headers []string
+	for _, column := range layer.Columns {
+		headers = append(headers, column.Header)
+	}
+	if expected := []string{"actief", "Diepte & <max> [m]", "Naam van de put"}; !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Headers were %v, expected %v", headers, expected)
+	}
+	if header := "<th>Diepte &amp; &lt;max&gt; &#91;m&#93;</th>"; !strings.Contains(generateHTML(layer).String(), header) {
+		t.Errorf("Template doesn't contain the escaped header %s:\n%s", header, generateHTML(layer).String())
+	}
+	if expected := []codeLabel{{Code: "1", Label: "Ja"}, {Code: "0", Label: "Nee"}}; layer.Columns[0].CodeList == nil || !reflect.DeepEqual(layer.Columns[0].CodeList.Codes, expected) {
+		t.Errorf("Code list was %v, expected %v", layer.Columns[0].CodeList, expected)
+	}
+}
+
+func Test_applyQGISStyle_config(t *testing.T) {
+	shown := false
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {
+		Order: []string{"naam"},
+		Columns: map[string]columnConfig{
+			"fid":  {Hidden: &shown},
+			"naam": {Header: "Naam"},
+		},
+	}}}
+	layer := testStyleLayer(t, config)
+	var headers []string
+	for _, column := range layer.Columns {
+		headers = append(headers, column.Header)
+	}
+	if expected := []string{"Naam", "actief", "Diepte & <max> [m]", "fid"}; !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Headers were %v, expected %v", headers, expected)
+	}
+}
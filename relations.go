@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var joinNameRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// One-to-many relation of the Related Tables extension, rendered through a MapServer JOIN.
+// MapServer names the columns of a CSV join by position, the first is the id of the base feature.
+type templateRelation struct {
+	Name                 string
+	Title                string
+	BaseColumn           string
+	RelatedTable         string
+	RelatedPrimaryColumn string
+	MappingTable         string
+	Columns              []templateColumn
+}
+
+// Find the relations of a layer in gpkgext_relations, with the columns of the related tables that aren't personal data
+func getRelationsForLayer(layer string, geopackage *sql.DB, geomColumns []string, options *templateOptions) []templateRelation {
+	var table string
+	err := geopackage.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'gpkgext_relations'").Scan(&table)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	rows, err := geopackage.Query("SELECT base_primary_column, related_table_name, related_primary_column, mapping_table_name FROM gpkgext_relations WHERE base_table_name = ? ORDER BY id", layer)
+	if err != nil {
+		log.Fatal("Error with querying Geopackage: ", err)
+	}
+	var relations []templateRelation
+	used := make(map[string]bool)
+	for rows.Next() {
+		var relation templateRelation
+		if err = rows.Scan(&relation.BaseColumn, &relation.RelatedTable, &relation.RelatedPrimaryColumn, &relation.MappingTable); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		relation.Name = joinNameRegexp.ReplaceAllString(relation.RelatedTable, "_")
+		if used[relation.Name] {
+			relation.Name = joinNameRegexp.ReplaceAllString(relation.MappingTable, "_")
+		}
+		used[relation.Name] = true
+		relation.Title = relation.RelatedTable
+		relations = append(relations, relation)
+	}
+	rows.Close()
+	for i, relation := range relations {
+		log.Printf("Related table %s found for layer %s through %s", relation.RelatedTable, layer, relation.MappingTable)
+		related := newTemplateLayer(relation.RelatedTable, getPropertiesFromLayer(relation.RelatedTable, geopackage), geomColumns)
+		related.setColumnTypes(getColumnInfoFromLayer(relation.RelatedTable, geopackage))
+		applyPersonalDataPolicy(&related, geopackage, options)
+		for _, column := range related.Columns {
+			column.Tag = "[" + relation.Name + "_" + strconv.Itoa(len(relations[i].Columns)+2) + "]"
+			relations[i].Columns = append(relations[i].Columns, column)
+		}
+	}
+	return relations
+}
+
+// SQL that selects the related records with the id of the base feature first, the source of the CSV for the join
+func (r templateRelation) exportSQL() string {
+	var columns []string
+	for _, column := range r.Columns {
+		columns = append(columns, "r."+quoteIdentifier(column.Name))
+	}
+	return "SELECT m.base_id, " + strings.Join(columns, ", ") + " FROM " + quoteIdentifier(r.MappingTable) + " m JOIN " +
+		quoteIdentifier(r.RelatedTable) + " r ON r." + quoteIdentifier(r.RelatedPrimaryColumn) + " = m.related_id ORDER BY m.base_id"
+}
+
+// Join template with a row per related record, MapServer renders it once for every record
+func generateJoinTemplate(relation templateRelation) string {
+	var buf strings.Builder
+	buf.WriteString("\t\t\t\t<tr>\n")
+	for _, column := range relation.Columns {
+		buf.WriteString("\t\t\t\t\t<td>" + column.Tag + "</td>\n")
+	}
+	buf.WriteString("\t\t\t\t</tr>\n")
+	return buf.String()
+}
+
+// Nested table with the related records of a feature, MapServer fills it through the [join_...] tag
+func generateRelatedHTML(relation templateRelation) string {
+	var buf strings.Builder
+	buf.WriteString("\t\t<table class=\"featureInfo related\">\n")
+	buf.WriteString("\t\t\t<caption class=\"featureInfo\">" + mapserverText(relation.Title) + "</caption>\n\t\t\t<tr>\n")
+	for _, column := range relation.Columns {
+		buf.WriteString("\t\t\t\t<th>" + mapserverText(column.Header) + "</th>\n")
+	}
+	buf.WriteString("\t\t\t</tr>\n[join_" + relation.Name + "]\t\t</table>\n")
+	return buf.String()
+}
+
+// Mapfile JOIN for a relation, with the command that exports the related records to the CSV it reads
+func generateJoinMapfile(layer templateLayer, relation templateRelation, dataset string, joinTemplate string) string {
+	csvFile := strings.TrimSuffix(joinTemplate, path.Ext(joinTemplate)) + ".csv"
+	var buf strings.Builder
+	buf.WriteString("# Related records of " + layer.Name + " in " + relation.RelatedTable + ", export them with:\n")
+	buf.WriteString("# ogr2ogr -f CSV -lco HEADERS=NO " + csvFile + " " + dataset + ".gpkg -sql '" + strings.Replace(relation.exportSQL(), "'", "'\\''", -1) + "'\n")
+	buf.WriteString("JOIN\n")
+	buf.WriteString("\tNAME \"" + relation.Name + "\"\n")
+	buf.WriteString("\tTABLE \"" + csvFile + "\"\n")
+	buf.WriteString("\tCONNECTIONTYPE CSV\n")
+	buf.WriteString("\tFROM \"" + relation.BaseColumn + "\"\n")
+	buf.WriteString("\tTO \"1\"\n")
+	buf.WriteString("\tTYPE ONE-TO-MANY\n")
+	buf.WriteString("\tTEMPLATE \"" + joinTemplate + "\"\n")
+	buf.WriteString("END\n")
+	return buf.String()
+}
+
+// Files for the relations of a layer: a join template and a mapfile JOIN per relation. They are named
+// <layer>.join.<relation>, as <layer>_<relation> is often the mapping table that gets its own templates.
+func relationFiles(layer templateLayer, naming outputNaming) []generatedFile {
+	var files []generatedFile
+	for _, relation := range layer.Relations {
+		joinTemplate := outputFileName(naming.pattern, naming.dataset, layer.Name+".join."+relation.Name, "html")
+		mapfile := outputFileName(naming.pattern, naming.dataset, layer.Name+".join."+relation.Name, "map")
+		files = append(files,
+			generatedFile{name: joinTemplate, content: []byte(generateJoinTemplate(relation))},
+			generatedFile{name: mapfile, content: []byte(generateJoinMapfile(layer, relation, naming.dataset, path.Base(joinTemplate)))},
+		)
+	}
+	return files
+}
+
+// Render the related records of features into their joins, like MapServer does with the JOIN of a relation
+func addRelatedRecords(features []templateFeature, layer templateLayer, geopackage *sql.DB) {
+	for _, relation := range layer.Relations {
+		joinTemplate := generateJoinTemplate(relation)
+		for i := range features {
+			rows, err := geopackage.Query("SELECT * FROM ("+relation.exportSQL()+") WHERE base_id = ?", features[i].Values[relation.BaseColumn])
+			if err != nil {
+				log.Fatal("Error with querying Geopackage: ", err)
+			}
+			_, values, err := readRows(rows)
+			if err != nil {
+				log.Fatal("Error with querying Geopackage: ", err)
+			}
+			var records []templateFeature
+			for _, row := range values {
+				record := templateFeature{Values: make(map[string]string)}
+				for j, value := range row {
+					record.Values[relation.Name+"_"+strconv.Itoa(j+1)] = templateValue(value)
+				}
+				records = append(records, record)
+			}
+			if features[i].Joins == nil {
+				features[i].Joins = make(map[string]string)
+			}
+			features[i].Joins[relation.Name] = renderMapserverTemplate(joinTemplate, "", records)
+		}
+	}
+}
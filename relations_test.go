@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_relations(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	statements := []string{
+		"CREATE TABLE inspecties (id INTEGER PRIMARY KEY AUTOINCREMENT, datum DATE, opmerking TEXT)",
+		"INSERT INTO inspecties (datum, opmerking) VALUES ('2022-01-01', 'Verstopt'), ('2023-01-01', 'In orde & schoon'), ('2023-06-01', 'Andere put')",
+		"CREATE TABLE putten_inspecties (base_id INTEGER NOT NULL, related_id INTEGER NOT NULL)",
+		"INSERT INTO putten_inspecties VALUES (1, 1), (1, 2), (2, 3)",
+		"CREATE TABLE gpkgext_relations (id INTEGER PRIMARY KEY AUTOINCREMENT, base_table_name TEXT NOT NULL, base_primary_column TEXT NOT NULL DEFAULT 'id', related_table_name TEXT NOT NULL, related_primary_column TEXT NOT NULL DEFAULT 'id', relation_name TEXT NOT NULL, mapping_table_name TEXT NOT NULL UNIQUE)",
+		"INSERT INTO gpkgext_relations (base_table_name, base_primary_column, related_table_name, related_primary_column, relation_name, mapping_table_name) VALUES ('putten', 'fid', 'inspecties', 'id', 'attributes', 'putten_inspecties')",
+	}
+	for _, statement := range statements {
+		if _, err := geopackage.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	layer := newTemplateLayer("putten", []string{"fid", "naam"}, nil)
+	layer.Relations = getRelationsForLayer("putten", geopackage, []string{"geom"}, &templateOptions{})
+	if len(layer.Relations) != 1 || layer.Relations[0].Name != "inspecties" || len(layer.Relations[0].Columns) != 3 {
+		t.Fatalf("Unexpected relations: %v", layer.Relations)
+	}
+
+	features := getSampleFeatures(layer, geopackage, 1)
+	addRelatedRecords(features, layer, geopackage)
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", features)
+	expected := "\t\t\t\t<th>opmerking</th>\n\t\t\t</tr>\n" +
+		"\t\t\t\t<tr>\n\t\t\t\t\t<td>1</td>\n\t\t\t\t\t<td>2022-01-01</td>\n\t\t\t\t\t<td>Verstopt</td>\n\t\t\t\t</tr>\n" +
+		"\t\t\t\t<tr>\n\t\t\t\t\t<td>2</td>\n\t\t\t\t\t<td>2023-01-01</td>\n\t\t\t\t\t<td>In orde &amp; schoon</td>\n\t\t\t\t</tr>\n\t\t</table>\n"
+	if !strings.Contains(rendered, expected) || strings.Contains(rendered, "Andere put") {
+		t.Errorf("Rendered template doesn't contain the related records:\n%s", rendered)
+	}
+
+	files := relationFiles(layer, outputNaming{pattern: defaultFileNamePattern, dataset: "afvalwater"})
+	if len(files) != 2 || files[0].name != "putten.join.inspecties.html" || files[1].name != "putten.join.inspecties.map" {
+		t.Fatalf("Unexpected relation files: %v", files)
+	}
+	for _, expected := range []string{`NAME "inspecties"`, `TABLE "putten.join.inspecties.csv"`, `FROM "fid"`, `TEMPLATE "putten.join.inspecties.html"`, "TYPE ONE-TO-MANY"} {
+		if !strings.Contains(string(files[1].content), expected) {
+			t.Errorf("Mapfile JOIN doesn't contain %s:\n%s", expected, files[1].content)
+		}
+	}
+}
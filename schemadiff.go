@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Declared column of a layer
+type columnInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Metadata of a layer from gpkg_contents and gpkg_spatial_ref_sys
+type layerMetadata struct {
+	DataType    string `json:"dataType"`
+	Identifier  string `json:"identifier"`
+	Description string `json:"description"`
+	SrsID       int64  `json:"srsId"`
+	SrsName     string `json:"srsName"`
+}
+
+// Layers, columns and metadata of a Geopackage
+type geopackageSchema struct {
+	Layers   []string
+	Columns  map[string][]columnInfo
+	Metadata map[string]layerMetadata
+}
+
+type rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type valueChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Differences of a layer that exists in both Geopackages
+type layerDiff struct {
+	Layer           string        `json:"layer"`
+	AddedColumns    []columnInfo  `json:"addedColumns,omitempty"`
+	RemovedColumns  []columnInfo  `json:"removedColumns,omitempty"`
+	RenamedColumns  []rename      `json:"renamedColumns,omitempty"`
+	TypeChanges     []valueChange `json:"typeChanges,omitempty"`
+	MetadataChanges []valueChange `json:"metadataChanges,omitempty"`
+}
+
+// Differences between two Geopackages
+type schemaDiff struct {
+	Old           string      `json:"old"`
+	New           string      `json:"new"`
+	AddedLayers   []string    `json:"addedLayers,omitempty"`
+	RemovedLayers []string    `json:"removedLayers,omitempty"`
+	RenamedLayers []rename    `json:"renamedLayers,omitempty"`
+	ChangedLayers []layerDiff `json:"changedLayers,omitempty"`
+}
+
+// Compare the schemas of two Geopackages
+func schemaDiffCommand(args []string) {
+	startTime := time.Now()
+	flags := flag.NewFlagSet("schema-diff", flag.ExitOnError)
+	oldParam := flags.String("old", "", "URL or path of the old Geopackage")
+	newParam := flags.String("new", "", "URL or path of the new Geopackage")
+	formatParam := flags.String("format", "text", "Report format: text, markdown or json")
+	flags.Parse(args)
+	if *oldParam == "" || *newParam == "" {
+		log.Fatal("Error: old and new are required. Run schema-diff with -h for help.")
+	}
+	if *formatParam != "text" && *formatParam != "markdown" && *formatParam != "json" {
+		log.Fatal("Error: unknown format " + *formatParam)
+	}
+	diff := compareSchemas(readSchemaFromLocation(*oldParam), readSchemaFromLocation(*newParam))
+	diff.Old = *oldParam
+	diff.New = *newParam
+	writeSchemaDiff(os.Stdout, diff, *formatParam)
+	programFinishedSuccesfully(startTime)
+}
+
+// Read the schema of the Geopackage at an URL or path
+func readSchemaFromLocation(location string) geopackageSchema {
+	gpkgURLParam, gpkgPathParam := gpkgLocationParams(location)
+	gpkgFile := getGpkgFile(gpkgURLParam, gpkgPathParam)
+	geopackage := openGeopackage(gpkgFile)
+	schema := readSchema(geopackage)
+	geopackage.Close()
+	gpkgFile.Close()
+	cleanup(gpkgFile, gpkgURLParam)
+	return schema
+}
+
+// Read layers, columns and metadata from Geopackage
+func readSchema(geopackage *sql.DB) geopackageSchema {
+	schema := geopackageSchema{
+		Layers:   getLayersFromGeopackage(geopackage),
+		Columns:  make(map[string][]columnInfo),
+		Metadata: getLayerMetadataFromGeopackage(geopackage),
+	}
+	for _, layer := range schema.Layers {
+		schema.Columns[layer] = getColumnInfoFromLayer(layer, geopackage)
+	}
+	return schema
+}
+
+// Read the declared columns of a layer
+func getColumnInfoFromLayer(layer string, geopackage *sql.DB) []columnInfo {
+	rows, errDb := geopackage.Query("PRAGMA table_info(" + quoteIdentifier(layer) + ")")
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	defer rows.Close()
+	var columns []columnInfo
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		columns = append(columns, columnInfo{Name: name, Type: strings.ToUpper(columnType)})
+	}
+	return columns
+}
+
+// Read the metadata of all layers
+func getLayerMetadataFromGeopackage(geopackage *sql.DB) map[string]layerMetadata {
+	rows, errDb := geopackage.Query(`SELECT c.table_name, c.data_type, c.identifier, c.description, c.srs_id, s.srs_name
+		FROM gpkg_contents c LEFT JOIN gpkg_spatial_ref_sys s ON c.srs_id = s.srs_id`)
+	if errDb != nil {
+		log.Fatal("Error with querying Geopackage: ", errDb)
+	}
+	defer rows.Close()
+	metadata := make(map[string]layerMetadata)
+	for rows.Next() {
+		var layer string
+		var dataType, identifier, description, srsName sql.NullString
+		var srsID sql.NullInt64
+		if err := rows.Scan(&layer, &dataType, &identifier, &description, &srsID, &srsName); err != nil {
+			log.Fatal("Error with querying Geopackage: ", err)
+		}
+		metadata[layer] = layerMetadata{
+			DataType:    dataType.String,
+			Identifier:  identifier.String,
+			Description: description.String,
+			SrsID:       srsID.Int64,
+			SrsName:     srsName.String,
+		}
+	}
+	return metadata
+}
+
+// Quote an identifier for use in an SQL statement
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.Replace(identifier, `"`, `""`, -1) + `"`
+}
+
+// Compare two schemas, layers and columns that were removed and added with the same structure count as renamed
+func compareSchemas(oldSchema geopackageSchema, newSchema geopackageSchema) schemaDiff {
+	var diff schemaDiff
+	var removed, added []string
+	for _, layer := range oldSchema.Layers {
+		if _, ok := newSchema.Columns[layer]; !ok {
+			removed = append(removed, layer)
+		}
+	}
+	for _, layer := range newSchema.Layers {
+		if _, ok := oldSchema.Columns[layer]; !ok {
+			added = append(added, layer)
+		}
+	}
+	for _, oldLayer := range removed {
+		renamed := false
+		for i, newLayer := range added {
+			if sameColumns(oldSchema.Columns[oldLayer], newSchema.Columns[newLayer]) {
+				diff.RenamedLayers = append(diff.RenamedLayers, rename{From: oldLayer, To: newLayer})
+				added = append(added[:i], added[i+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			diff.RemovedLayers = append(diff.RemovedLayers, oldLayer)
+		}
+	}
+	diff.AddedLayers = added
+
+	for _, layer := range oldSchema.Layers {
+		if _, ok := newSchema.Columns[layer]; !ok {
+			continue
+		}
+		layerDiff := compareColumns(layer, oldSchema.Columns[layer], newSchema.Columns[layer])
+		layerDiff.MetadataChanges = compareMetadata(oldSchema.Metadata[layer], newSchema.Metadata[layer])
+		if !layerDiff.empty() {
+			diff.ChangedLayers = append(diff.ChangedLayers, layerDiff)
+		}
+	}
+	return diff
+}
+
+// Compare the columns of a layer
+func compareColumns(layer string, oldColumns []columnInfo, newColumns []columnInfo) layerDiff {
+	diff := layerDiff{Layer: layer}
+	oldByName := make(map[string]columnInfo)
+	for _, column := range oldColumns {
+		oldByName[column.Name] = column
+	}
+	newByName := make(map[string]columnInfo)
+	for _, column := range newColumns {
+		newByName[column.Name] = column
+	}
+	var removed, added []columnInfo
+	for _, column := range oldColumns {
+		if newColumn, ok := newByName[column.Name]; !ok {
+			removed = append(removed, column)
+		} else if newColumn.Type != column.Type {
+			diff.TypeChanges = append(diff.TypeChanges, valueChange{Name: column.Name, Old: column.Type, New: newColumn.Type})
+		}
+	}
+	for _, column := range newColumns {
+		if _, ok := oldByName[column.Name]; !ok {
+			added = append(added, column)
+		}
+	}
+	for _, oldColumn := range removed {
+		renamed := false
+		for i, newColumn := range added {
+			if oldColumn.Type == newColumn.Type && columnIndex(oldColumns, oldColumn.Name) == columnIndex(newColumns, newColumn.Name) {
+				diff.RenamedColumns = append(diff.RenamedColumns, rename{From: oldColumn.Name, To: newColumn.Name})
+				added = append(added[:i], added[i+1:]...)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			diff.RemovedColumns = append(diff.RemovedColumns, oldColumn)
+		}
+	}
+	diff.AddedColumns = added
+	return diff
+}
+
+// Compare the metadata of a layer
+func compareMetadata(oldMetadata layerMetadata, newMetadata layerMetadata) []valueChange {
+	var changes []valueChange
+	compare := func(name string, oldValue string, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, valueChange{Name: name, Old: oldValue, New: newValue})
+		}
+	}
+	compare("data_type", oldMetadata.DataType, newMetadata.DataType)
+	compare("identifier", oldMetadata.Identifier, newMetadata.Identifier)
+	compare("description", oldMetadata.Description, newMetadata.Description)
+	compare("srs_id", fmt.Sprint(oldMetadata.SrsID), fmt.Sprint(newMetadata.SrsID))
+	compare("srs_name", oldMetadata.SrsName, newMetadata.SrsName)
+	return changes
+}
+
+func (d layerDiff) empty() bool {
+	return len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 && len(d.RenamedColumns) == 0 &&
+		len(d.TypeChanges) == 0 && len(d.MetadataChanges) == 0
+}
+
+func (d schemaDiff) empty() bool {
+	return len(d.AddedLayers) == 0 && len(d.RemovedLayers) == 0 && len(d.RenamedLayers) == 0 && len(d.ChangedLayers) == 0
+}
+
+func sameColumns(a []columnInfo, b []columnInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func columnIndex(columns []columnInfo, name string) int {
+	for i, column := range columns {
+		if column.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Write a schema diff as text, Markdown or JSON
+func writeSchemaDiff(out io.Writer, diff schemaDiff, format string) {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			log.Fatal("Cannot write schema diff: ", err)
+		}
+		return
+	}
+	markdown := format == "markdown"
+	if markdown {
+		fmt.Fprintf(out, "# Schema diff\n\n`%s` → `%s`\n", diff.Old, diff.New)
+	} else {
+		fmt.Fprintf(out, "Schema diff %s -> %s\n", diff.Old, diff.New)
+	}
+	if diff.empty() {
+		fmt.Fprintln(out, "No differences found.")
+		return
+	}
+	section := func(title string) {
+		if markdown {
+			fmt.Fprintf(out, "\n## %s\n\n", title)
+		} else {
+			fmt.Fprintf(out, "\n%s\n", title)
+		}
+	}
+	item := func(format string, args ...interface{}) {
+		if markdown {
+			fmt.Fprintf(out, "- "+format+"\n", args...)
+		} else {
+			fmt.Fprintf(out, "  "+format+"\n", args...)
+		}
+	}
+	if len(diff.AddedLayers) > 0 {
+		section("Added layers")
+		for _, layer := range diff.AddedLayers {
+			item("%s", layer)
+		}
+	}
+	if len(diff.RemovedLayers) > 0 {
+		section("Removed layers")
+		for _, layer := range diff.RemovedLayers {
+			item("%s", layer)
+		}
+	}
+	if len(diff.RenamedLayers) > 0 {
+		section("Renamed layers")
+		for _, r := range diff.RenamedLayers {
+			item("%s -> %s", r.From, r.To)
+		}
+	}
+	sort.Slice(diff.ChangedLayers, func(i, j int) bool { return diff.ChangedLayers[i].Layer < diff.ChangedLayers[j].Layer })
+	for _, layer := range diff.ChangedLayers {
+		section("Changed layer " + layer.Layer)
+		for _, column := range layer.AddedColumns {
+			item("added column %s (%s)", column.Name, column.Type)
+		}
+		for _, column := range layer.RemovedColumns {
+			item("removed column %s (%s)", column.Name, column.Type)
+		}
+		for _, r := range layer.RenamedColumns {
+			item("renamed column %s -> %s", r.From, r.To)
+		}
+		for _, change := range layer.TypeChanges {
+			item("type of column %s changed: %s -> %s", change.Name, change.Old, change.New)
+		}
+		for _, change := range layer.MetadataChanges {
+			item("%s changed: %q -> %q", change.Name, change.Old, change.New)
+		}
+	}
+}
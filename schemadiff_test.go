@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_readSchema(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	schema := readSchema(geopackage)
+	expectedColumns := []columnInfo{
+		{"fid", "INTEGER"}, {"geom", "POINT"}, {"naam", "TEXT"}, {"diepte", "REAL"}, {"bouwdatum", "DATE"}, {"actief", "BOOLEAN"},
+	}
+	if !reflect.DeepEqual(schema.Columns["putten"], expectedColumns) {
+		t.Errorf("Unexpected columns: %v", schema.Columns["putten"])
+	}
+	if schema.Metadata["putten"].SrsName != "Amersfoort / RD New" {
+		t.Errorf("Unexpected metadata: %v", schema.Metadata["putten"])
+	}
+}
+
+func Test_compareSchemas(t *testing.T) {
+	oldSchema := geopackageSchema{
+		Layers: []string{"putten", "leidingen", "gemalen"},
+		Columns: map[string][]columnInfo{
+			"putten":    {{"fid", "INTEGER"}, {"naam", "TEXT"}, {"diepte", "REAL"}, {"type", "TEXT"}},
+			"leidingen": {{"fid", "INTEGER"}, {"lengte", "REAL"}},
+			"gemalen":   {{"fid", "INTEGER"}},
+		},
+		Metadata: map[string]layerMetadata{"putten": {Identifier: "Putten", SrsID: 28992}},
+	}
+	newSchema := geopackageSchema{
+		Layers: []string{"putten", "riolering", "overstorten"},
+		Columns: map[string][]columnInfo{
+			"putten":      {{"fid", "INTEGER"}, {"name", "TEXT"}, {"diepte", "INTEGER"}, {"beheerder", "INTEGER"}},
+			"riolering":   {{"fid", "INTEGER"}, {"lengte", "REAL"}},
+			"overstorten": {{"fid", "INTEGER"}, {"naam", "TEXT"}},
+		},
+		Metadata: map[string]layerMetadata{"putten": {Identifier: "Rioolputten", SrsID: 28992}},
+	}
+	diff := compareSchemas(oldSchema, newSchema)
+	expected := schemaDiff{
+		AddedLayers:   []string{"overstorten"},
+		RemovedLayers: []string{"gemalen"},
+		RenamedLayers: []rename{{From: "leidingen", To: "riolering"}},
+		ChangedLayers: []layerDiff{{
+			Layer:           "putten",
+			AddedColumns:    []columnInfo{{"beheerder", "INTEGER"}},
+			RemovedColumns:  []columnInfo{{"type", "TEXT"}},
+			RenamedColumns:  []rename{{From: "naam", To: "name"}},
+			TypeChanges:     []valueChange{{Name: "diepte", Old: "REAL", New: "INTEGER"}},
+			MetadataChanges: []valueChange{{Name: "identifier", Old: "Putten", New: "Rioolputten"}},
+		}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Result was not OK.\nResult:\n%+v\nExpected:\n%+v", diff, expected)
+	}
+}
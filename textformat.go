@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// MapServer text/plain template: a block of aligned key: value lines per feature
+func textFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	fileName := outputFileName(naming.pattern, naming.dataset, layer.Name, "txt")
+	return []generatedFile{{name: fileName, content: []byte(generateText(layer))}}
+}
+
+// MapServer text/csv template: a header row and a line per feature
+func csvFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	fileName := outputFileName(naming.pattern, naming.dataset, layer.Name, "csv")
+	return []generatedFile{{name: fileName, content: []byte(generateCSV(layer))}}
+}
+
+// Values MapServer can write in a quoted CSV field, it can't double the quotes inside a value
+// and line breaks would split the row
+const csvValuePattern = `^[^"[:cntrl:]]*$`
+
+// Generate a text/plain template for a layer, every new line outside the tags ends up in the output
+func generateText(layer templateLayer) string {
+	log.Print("Generate text for layer: " + layer.Name)
+	var headers []string
+	width := 0
+	for _, column := range layer.Columns {
+		header := escapeStatic(column.Header, "none")
+		if length := utf8.RuneCountInString(header); length > width {
+			width = length
+		}
+		headers = append(headers, header)
+	}
+	var buf strings.Builder
+	buf.WriteString(templateMagic)
+	buf.WriteString(`[resultset layer="` + layer.Name + `"]` + escapeStatic(layer.Title, "none") + "\n")
+	buf.WriteString("[feature]\n")
+	for i, column := range layer.Columns {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(headers[i]))
+		buf.WriteString(headers[i] + padding + ": " + mapserverValue(column, "none") + "\n")
+	}
+	buf.WriteString("[/feature][/resultset]")
+	return buf.String()
+}
+
+// Generate a text/csv template for a layer, every field is quoted.
+// MapServer can't double the quotes inside attribute values, so values with a double quote or a line break are left out.
+func generateCSV(layer templateLayer) string {
+	log.Print("Generate CSV for layer: " + layer.Name)
+	log.Printf("Values with a double quote or a line break can't be quoted by MapServer and are left out of the CSV template of layer %s", layer.Name)
+	var headers, values []string
+	for _, column := range layer.Columns {
+		headers = append(headers, `"`+escapeStatic(column.Header, "csv")+`"`)
+		values = append(values, `"`+mapserverValue(column, "csv")+`"`)
+	}
+	var buf strings.Builder
+	buf.WriteString(templateMagic)
+	buf.WriteString(`[resultset layer="` + layer.Name + `"]` + strings.Join(headers, ",") + "\r\n")
+	buf.WriteString("[feature]" + strings.Join(values, ",") + "\r\n")
+	buf.WriteString("[/feature][/resultset]")
+	return buf.String()
+}
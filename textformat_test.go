@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func testTextLayer() templateLayer {
+	layer := newTemplateLayer("putten", []string{"fid", "geom", "naam", "diepte"}, []string{"geom"})
+	layer.Columns = append(layer.Columns, templateColumn{Header: "bron", Static: `PDOK "putten"`})
+	return layer
+}
+
+var testTextFeatures = []templateFeature{
+	{Values: map[string]string{"fid": "1", "naam": "Put <1>", "diepte": "2.5"}},
+	{Values: map[string]string{"fid": "2", "naam": "Put, 2", "diepte": ""}},
+}
+
+func Test_generateText(t *testing.T) {
+	rendered := renderMapserverTemplate(generateText(testTextLayer()), "putten", testTextFeatures)
+	expected := "putten\n" +
+		"\nfid   : 1\nnaam  : Put <1>\ndiepte: 2.5\nbron  : PDOK \"putten\"\n" +
+		"\nfid   : 2\nnaam  : Put, 2\ndiepte: \nbron  : PDOK \"putten\"\n"
+	if rendered != expected {
+		t.Errorf("Result was not OK.\nResult:\n%q\nExpected:\n%q", rendered, expected)
+	}
+}
+
+func Test_generateCSV(t *testing.T) {
+	rendered := renderMapserverTemplate(generateCSV(testTextLayer()), "putten", testTextFeatures)
+	expected := "\"fid\",\"naam\",\"diepte\",\"bron\"\r\n" +
+		"\"1\",\"Put <1>\",\"2.5\",\"PDOK \"\"putten\"\"\"\r\n" +
+		"\"2\",\"Put, 2\",\"\",\"PDOK \"\"putten\"\"\"\r\n"
+	if rendered != expected {
+		t.Errorf("Result was not OK.\nResult:\n%q\nExpected:\n%q", rendered, expected)
+	}
+}
+
+func Test_generateCSV_unquotable(t *testing.T) {
+	layer := testTextLayer()
+	layer.Columns[1].Header = "naam [officieel]"
+	features := []templateFeature{{Values: map[string]string{"fid": "1", "naam": "Put \"1\"\nnoord", "diepte": "2.5"}}}
+	rendered := renderMapserverTemplate(generateCSV(layer), "putten", features)
+	expected := "\"fid\",\"naam &#91;officieel&#93;\",\"diepte\",\"bron\"\r\n" +
+		"\"1\",\"\",\"2.5\",\"PDOK \"\"putten\"\"\"\r\n"
+	if rendered != expected {
+		t.Errorf("Result was not OK.\nResult:\n%q\nExpected:\n%q", rendered, expected)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"log"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const defaultPageTitle = "GetFeatureInfo output"
+
+var languageRegexp = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// Fixed texts of the templates per language, the config can override them
+var uiStrings = map[string]map[string]string{
+	"nl": {"title": "GetFeatureInfo resultaat", "geometryType": "Geometrietype", "coordinate": "Coördinaat", "area": "Oppervlakte", "length": "Lengte"},
+	"en": {"title": defaultPageTitle, "geometryType": "Geometry type", "coordinate": "Coordinate", "area": "Area", "length": "Length"},
+	"de": {"title": "GetFeatureInfo Ergebnis", "geometryType": "Geometrietyp", "coordinate": "Koordinate", "area": "Fläche", "length": "Länge"},
+	"fr": {"title": "Résultat GetFeatureInfo", "geometryType": "Type de géométrie", "coordinate": "Coordonnée", "area": "Surface", "length": "Longueur"},
+}
+
+// Translations of a language in the configuration file: headers by alias or column name, titles by layer title or name
+// and the fixed texts of the templates
+type translationConfig struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Titles  map[string]string `json:"titles,omitempty"`
+	UI      map[string]string `json:"ui,omitempty"`
+}
+
+// Languages given with -lang, nil when the templates aren't made for a language
+func (o *templateOptions) languages() []string {
+	var languages []string
+	for _, language := range strings.Split(o.lang, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// Check the languages and the fixed texts the config translates
+func checkLanguages(languages []string, config *templateConfig) {
+	for _, language := range languages {
+		if !languageRegexp.MatchString(language) {
+			log.Fatal("Error: unknown language " + language + ", use a language code like nl or en")
+		}
+	}
+	for language, translation := range config.Translations {
+		for key := range translation.UI {
+			if _, ok := uiStrings["en"][key]; !ok {
+				log.Fatalf("Error: unknown text %s in the %s translations of the config", key, language)
+			}
+		}
+	}
+}
+
+// Copy of a layer with its title, headers and fixed texts in a language, nothing changes without a language
+func translateLayer(layer templateLayer, language string, config *templateConfig) templateLayer {
+	if language == "" {
+		return layer
+	}
+	var translation translationConfig
+	if config != nil {
+		translation = config.Translations[language]
+	}
+	layer.Language = language
+	layer.PageTitle = translatedUIString(language, "title", translation)
+	layer.Title = translate(translation.Titles, layer.Title, layer.Name)
+	layer.Columns = translateColumns(layer.Columns, language, translation)
+	var relations []templateRelation
+	for _, relation := range layer.Relations {
+		relation.Title = translate(translation.Titles, relation.Title, relation.RelatedTable)
+		relation.Columns = translateColumns(relation.Columns, language, translation)
+		relations = append(relations, relation)
+	}
+	layer.Relations = relations
+	return layer
+}
+
+// Copies of columns with translated headers and code list labels, nested columns of JSON values included. Headers that are fixed texts
+// like those of the geometry summary are translated like the page title.
+func translateColumns(columns []templateColumn, language string, translation translationConfig) []templateColumn {
+	var translated []templateColumn
+	for _, column := range columns {
+		if column.UIText != "" {
+			column.Header = translatedUIString(language, column.UIText, translation)
+		} else {
+			column.Header = translate(translation.Headers, column.Header, column.Name)
+		}
+		if column.Nested != nil {
+			column.Nested = translateColumns(column.Nested, language, translation)
+		}
+		if column.CodeList != nil {
+			column.CodeList = column.CodeList.translate(language)
+		}
+		translated = append(translated, column)
+	}
+	return translated
+}
+
+// Translation of a text, looked up by the text itself and then by the name it was made from
+func translate(dictionary map[string]string, text string, name string) string {
+	if translated, ok := dictionary[text]; ok {
+		return translated
+	}
+	if translated, ok := dictionary[name]; ok && name != "" {
+		return translated
+	}
+	return text
+}
+
+// Fixed text in a language, from the config or built in, in English for languages without one
+func translatedUIString(language string, key string, translation translationConfig) string {
+	if text, ok := translation.UI[key]; ok {
+		return text
+	}
+	if text, ok := uiStrings[language][key]; ok {
+		return text
+	}
+	return uiStrings["en"][key]
+}
+
+// Start of an HTML document with the lang attribute and the page title of the language of a layer,
+// escape escapes the title for the template language
+func htmlDocumentStart(start string, layer templateLayer, escape func(text string) string) string {
+	if layer.Language == "" {
+		return start
+	}
+	start = strings.Replace(start, "<html>", `<html lang="`+layer.Language+`">`, 1)
+	return strings.Replace(start, "<title>"+defaultPageTitle+"</title>", "<title>"+escape(layer.PageTitle)+"</title>", 1)
+}
+
+// Naming of the files of a language, when a run makes templates in several languages.
+// The pattern takes {lang}, patterns without it get the language before the extension, like putten.en.html.
+func languageNaming(naming outputNaming, language string) outputNaming {
+	naming.language = language
+	switch {
+	case strings.Contains(naming.pattern, "{lang}"):
+		naming.pattern = strings.Replace(naming.pattern, "{lang}", safePathElement(language), -1)
+	case strings.HasSuffix(naming.pattern, ".{format}"):
+		naming.pattern = strings.TrimSuffix(naming.pattern, ".{format}") + "." + safePathElement(language) + ".{format}"
+	default:
+		naming.pattern += "." + safePathElement(language)
+	}
+	return naming
+}
+
+// File name with the language of the naming before the extension, for files that don't follow the pattern
+func languageFileName(name string, naming outputNaming) string {
+	if naming.language == "" {
+		return name
+	}
+	extension := path.Ext(name)
+	return strings.TrimSuffix(name, extension) + "." + safePathElement(naming.language) + extension
+}
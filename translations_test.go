@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_translateLayer(t *testing.T) {
+	layer := templateLayer{Name: "putten", Title: "Putten", Columns: []templateColumn{
+		{Name: "naam", Header: "Naam"},
+		{Name: "diepte", Header: "Diepte (m)"},
+		{Name: "adres", Header: "adres", Nested: []templateColumn{{Name: "adres_straat", Header: "straat"}}},
+		{Name: "actief", Header: "actief"},
+	}}
+	config := &templateConfig{Translations: map[string]translationConfig{"en": {
+		Headers: map[string]string{"Naam": "Name", "Diepte (m)": "Depth (m)", "adres": "Address", "straat": "Street"},
+		Titles:  map[string]string{"putten": "Wells"},
+	}}}
+	translated := translateLayer(layer, "en", config)
+	var headers []string
+	for _, column := range translated.Columns {
+		headers = append(headers, column.Header)
+	}
+	if strings.Join(headers, ",") != "Name,Depth (m),Address,actief" || translated.Columns[2].Nested[0].Header != "Street" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	if translated.Title != "Wells" || translated.Language != "en" || translated.PageTitle != "GetFeatureInfo output" {
+		t.Errorf("Unexpected layer: %s %s %s", translated.Title, translated.Language, translated.PageTitle)
+	}
+	if layer.Columns[0].Header != "Naam" || layer.Columns[2].Nested[0].Header != "straat" {
+		t.Errorf("Translating changed the original layer")
+	}
+
+	dutch := translateLayer(layer, "nl", config)
+	start := htmlDocumentStart(htmlStart, dutch, mapserverText)
+	if !strings.Contains(start, `<html lang="nl">`) || !strings.Contains(start, "<title>GetFeatureInfo resultaat</title>") {
+		t.Errorf("Unexpected start of HTML:\n%s", start)
+	}
+	if htmlDocumentStart(htmlStart, layer, mapserverText) != htmlStart {
+		t.Errorf("HTML without a language changed")
+	}
+}
+
+func Test_translateLayer_escaped(t *testing.T) {
+	layer := newTemplateLayer("putten", []string{"naam"}, nil)
+	config := &templateConfig{Translations: map[string]translationConfig{"en": {
+		Headers: map[string]string{"naam": "Name & <label> [x]"},
+		Titles:  map[string]string{"putten": "Wells ${x}"},
+		UI:      map[string]string{"title": "Output <#if>"},
+	}}}
+	translated := translateLayer(layer, "en", config)
+	html := generateHTML(translated).String()
+	for _, expected := range []string{"<title>Output &lt;#if&gt;</title>", "<th>Name &amp; &lt;label&gt; &#91;x&#93;</th>"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Template doesn't contain %s:\n%s", expected, html)
+		}
+	}
+	ftl := htmlDocumentStart(ftlHeader, translated, freeMarkerText) + generateFreeMarkerContent(translated).String()
+	for _, expected := range []string{"<title>Output &lt;#if&gt;</title>", "<caption class=\"featureInfo\">Wells &#36;{x}</caption>"} {
+		if !strings.Contains(ftl, expected) {
+			t.Errorf("Template doesn't contain %s:\n%s", expected, ftl)
+		}
+	}
+}
+
+func Test_languageNaming(t *testing.T) {
+	naming := outputNaming{pattern: defaultFileNamePattern, dataset: "afvalwater", workspace: "ws", store: "st"}
+	english := languageNaming(naming, "en")
+	if name := outputFileName(english.pattern, english.dataset, "putten", "html"); name != "putten.en.html" {
+		t.Errorf("File name was %s, expected putten.en.html", name)
+	}
+	custom := languageNaming(outputNaming{pattern: "{lang}/{layer}.{format}"}, "de")
+	if name := outputFileName(custom.pattern, "", "putten", "xml"); name != "de/putten.xml" {
+		t.Errorf("File name was %s, expected de/putten.xml", name)
+	}
+	files := geoServerFormat(translateLayer(templateLayer{Name: "putten", Title: "putten"}, "en", nil), english)
+	if files[0].name != "workspaces/ws/st/putten/header.en.ftl" || !strings.Contains(string(files[0].content), `<html lang="en">`) {
+		t.Errorf("Unexpected GeoServer header: %s\n%s", files[0].name, files[0].content)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultLocale = "nl-NL"
+const defaultPrecision = 2
+
+// How the value of a column is shown. Dates use Java SimpleDateFormat patterns, GeoServer applies them and
+// MapServer templates show a column of the SQL view that formats them.
+type valueFormat struct {
+	Precision *int   `json:"precision,omitempty"`
+	True      string `json:"true,omitempty"`
+	False     string `json:"false,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Raw       bool   `json:"raw,omitempty"`
+}
+
+// Labels and date patterns of a locale
+type localeFormat struct {
+	True     string
+	False    string
+	Date     string
+	DateTime string
+}
+
+// Supported locales
+var locales = map[string]localeFormat{
+	"nl-NL": {True: "Ja", False: "Nee", Date: "dd-MM-yyyy", DateTime: "dd-MM-yyyy HH:mm"},
+	"en-GB": {True: "Yes", False: "No", Date: "dd/MM/yyyy", DateTime: "dd/MM/yyyy HH:mm"},
+	"en-US": {True: "Yes", False: "No", Date: "MM/dd/yyyy", DateTime: "MM/dd/yyyy h:mm a"},
+	"de-DE": {True: "Ja", False: "Nein", Date: "dd.MM.yyyy", DateTime: "dd.MM.yyyy HH:mm"},
+	"fr-FR": {True: "Oui", False: "Non", Date: "dd/MM/yyyy", DateTime: "dd/MM/yyyy HH:mm"},
+}
+
+func localeNames() []string {
+	var names []string
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check that a locale is supported
+func checkLocale(locale string) {
+	if _, ok := locales[locale]; !ok {
+		log.Fatalf("Error: unknown locale %s, supported locales are: %s", locale, strings.Join(localeNames(), ", "))
+	}
+}
+
+// Set the value formats of the columns of a layer from their types and the locale, and the configured overrides.
+// Formatting has to be asked for: without a locale only columns with a format in the config are formatted,
+// in the default locale, and the other values are shown as they are stored.
+func applyValueFormats(layer *templateLayer, locale string, config *templateConfig) {
+	layer.Locale = locale
+	for i, column := range layer.Columns {
+		if column.Name == "" || column.Static != "" || column.Tag != "" {
+			continue
+		}
+		override := config.column(layer.Name, column.Name)
+		configured := override != nil && override.valueFormat != (valueFormat{})
+		if locale == "" && !configured {
+			continue
+		}
+		columnLocale := locale
+		if columnLocale == "" {
+			columnLocale = defaultLocale
+		}
+		format := defaultValueFormat(column.Type, locales[columnLocale])
+		if configured {
+			format = mergeValueFormat(format, override.valueFormat)
+		}
+		if format != nil {
+			layer.Columns[i].Format = format
+		}
+		if format != nil && format.Date != "" {
+			layer.Columns[i].Formatted = addDateViewColumn(layer, column.Name, format.Date)
+		}
+	}
+}
+
+// Add a column with the date of a column in a pattern to the SQL view of a layer, MapServer can't format dates itself.
+// Returns the name of the view column, empty when the pattern has fields SQLite can't format.
+func addDateViewColumn(layer *templateLayer, column string, pattern string) string {
+	expression, ok := sqliteDateExpression(quoteIdentifier(layer.Name)+"."+quoteIdentifier(column), pattern)
+	if !ok {
+		log.Printf("Date pattern %s of column %s of layer %s can't be applied in MapServer templates, the value is shown as it is stored", pattern, column, layer.Name)
+		return ""
+	}
+	name := column + "_formatted"
+	layer.ViewColumns = append(layer.ViewColumns, viewColumn{Name: name, Expression: expression})
+	log.Printf("MapServer templates of layer %s show column %s through %s of the SQL view, create the view and serve the layer from it", layer.Name, column, name)
+	return name
+}
+
+// SQLite expression formatting a date column in a SimpleDateFormat pattern with strftime,
+// values that aren't dates are shown as they are. Not ok when the pattern has fields like month names.
+func sqliteDateExpression(column string, pattern string) (string, bool) {
+	var parts []string
+	literal := ""
+	addLiteral := func() {
+		if literal != "" {
+			parts = append(parts, "'"+strings.Replace(literal, "'", "''", -1)+"'")
+			literal = ""
+		}
+	}
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if r == '\'' {
+			// quoted text, two quotes are a quote
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == i+1 {
+				literal += "'"
+			} else {
+				literal += string(runes[i+1 : end])
+			}
+			i = end + 1
+			continue
+		}
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			literal += string(r)
+			i++
+			continue
+		}
+		count := 1
+		for i+count < len(runes) && runes[i+count] == r {
+			count++
+		}
+		field, ok := sqliteDateField(column, r, count)
+		if !ok {
+			return "", false
+		}
+		addLiteral()
+		parts = append(parts, field)
+		i += count
+	}
+	addLiteral()
+	if len(parts) == 0 {
+		return "", false
+	}
+	return "CASE WHEN julianday(" + column + ") IS NULL THEN " + column + " ELSE " + strings.Join(parts, " || ") + " END", true
+}
+
+// SQLite expression for a field of a SimpleDateFormat pattern, a letter repeated count times like yyyy or MM
+func sqliteDateField(column string, letter rune, count int) (string, bool) {
+	strftime := func(format string) string {
+		return "strftime('" + format + "', " + column + ")"
+	}
+	number := func(format string) string {
+		return "CAST(" + strftime(format) + " AS INTEGER)"
+	}
+	padded := func(format string) string {
+		if count == 1 {
+			return number(format)
+		}
+		return strftime(format)
+	}
+	switch {
+	case letter == 'y' && count == 2:
+		return "substr(" + strftime("%Y") + ", 3)", true
+	case letter == 'y':
+		return strftime("%Y"), true
+	case letter == 'M' && count <= 2:
+		return padded("%m"), true
+	case letter == 'd' && count <= 2:
+		return padded("%d"), true
+	case letter == 'H' && count <= 2:
+		return padded("%H"), true
+	case letter == 'm' && count <= 2:
+		return padded("%M"), true
+	case letter == 's' && count <= 2:
+		return padded("%S"), true
+	case letter == 'h' && count <= 2:
+		hour := "((" + number("%H") + " + 11) % 12 + 1)"
+		if count == 2 {
+			return "printf('%02d', " + hour + ")", true
+		}
+		return hour, true
+	case letter == 'a':
+		return "CASE WHEN " + number("%H") + " < 12 THEN 'AM' ELSE 'PM' END", true
+	}
+	return "", false
+}
+
+// Format of a column type in a locale, nil when the value is shown as it is
+func defaultValueFormat(columnType string, locale localeFormat) *valueFormat {
+	columnType = strings.ToUpper(columnType)
+	switch {
+	case isBooleanType(columnType):
+		return &valueFormat{True: locale.True, False: locale.False}
+	case columnType == "DATE":
+		return &valueFormat{Date: locale.Date}
+	case columnType == "DATETIME":
+		return &valueFormat{Date: locale.DateTime}
+	case isNumericType(columnType) && !strings.Contains(columnType, "INT"):
+		precision := defaultPrecision
+		return &valueFormat{Precision: &precision}
+	}
+	return nil
+}
+
+// Override the settings of a format that are set in the configuration, raw removes all formatting
+func mergeValueFormat(format *valueFormat, override valueFormat) *valueFormat {
+	if override.Raw {
+		return nil
+	}
+	merged := valueFormat{}
+	if format != nil {
+		merged = *format
+	}
+	if override.Precision != nil {
+		merged.Precision = override.Precision
+	}
+	if override.True != "" {
+		merged.True = override.True
+	}
+	if override.False != "" {
+		merged.False = override.False
+	}
+	if override.Date != "" {
+		merged.Date = override.Date
+	}
+	return &merged
+}
+
+// Whether booleans are shown with labels
+func (f *valueFormat) hasLabels() bool {
+	return f != nil && (f.True != "" || f.False != "")
+}
+
+// MapServer [if] blocks showing the labels of a boolean column, 1 is true and 0 is false
+func mapserverBooleanLabels(column templateColumn, escape string) string {
+	return `[if name="` + column.Name + `" oper="eq" value="1"]` + escapeStatic(column.Format.True, escape) + `[/if]` +
+		`[if name="` + column.Name + `" oper="eq" value="0"]` + escapeStatic(column.Format.False, escape) + `[/if]`
+}
+
+// FreeMarker built-in formatting the raw value of a column, empty when there is none
+func freeMarkerFormat(format *valueFormat) string {
+	switch {
+	case format == nil:
+		return ""
+	case format.hasLabels():
+		return `?string("` + freeMarkerString(format.True) + `", "` + freeMarkerString(format.False) + `")`
+	case format.Date != "":
+		return `?string("` + freeMarkerString(format.Date) + `")`
+	case format.Precision != nil:
+		pattern := "0"
+		if *format.Precision > 0 {
+			pattern += "." + strings.Repeat("0", *format.Precision)
+		}
+		return `?string("` + pattern + `")`
+	}
+	return ""
+}
+
+// FreeMarker locale setting for a locale like nl-NL
+func freeMarkerLocale(locale string) string {
+	return strings.Replace(locale, "-", "_", 1)
+}
+
+// Text of a precision attribute
+func precisionAttribute(precision int) string {
+	return ` precision="` + strconv.Itoa(precision) + `"`
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testFormattedLayer(config *templateConfig) templateLayer {
+	layer := newTemplateLayer("putten", []string{"fid", "naam", "diepte", "bouwdatum", "actief"}, nil)
+	layer.setColumnTypes([]columnInfo{{"fid", "INTEGER"}, {"naam", "TEXT"}, {"diepte", "REAL"}, {"bouwdatum", "DATE"}, {"actief", "BOOLEAN"}})
+	applyValueFormats(&layer, defaultLocale, config)
+	return layer
+}
+
+func Test_applyValueFormats(t *testing.T) {
+	layer := testFormattedLayer(nil)
+	expected := []string{
+		"[fid]",
+		"[naam]",
+		`[item name="diepte" precision="2" escape="html"]`,
+		"[bouwdatum_formatted]",
+		`[if name="actief" oper="eq" value="1"]Ja[/if][if name="actief" oper="eq" value="0"]Nee[/if]`,
+	}
+	for i, column := range layer.Columns {
+		if result := mapserverValue(column, "html"); result != expected[i] {
+			t.Errorf("Value of %s was %s, expected %s", column.Name, result, expected[i])
+		}
+	}
+	expectedFreeMarker := []string{
+		`${((feature["fid"].value)!"")?html}`,
+		`${((feature["naam"].value)!"")?html}`,
+		`${((feature["diepte"].rawValue?string("0.00"))!"")?html}`,
+		`${((feature["bouwdatum"].rawValue?string("dd-MM-yyyy"))!"")?html}`,
+		`${((feature["actief"].rawValue?string("Ja", "Nee"))!"")?html}`,
+	}
+	for i, column := range layer.Columns {
+		if result := freeMarkerValue(column); result != expectedFreeMarker[i] {
+			t.Errorf("FreeMarker value of %s was %s, expected %s", column.Name, result, expectedFreeMarker[i])
+		}
+	}
+}
+
+func Test_applyValueFormats_overrides(t *testing.T) {
+	precision := 0
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {Columns: map[string]columnConfig{
+		"diepte": {valueFormat: valueFormat{Precision: &precision}},
+		"actief": {valueFormat: valueFormat{True: "In gebruik & actief"}},
+		"fid":    {valueFormat: valueFormat{Raw: true}},
+	}}}}
+	layer := testFormattedLayer(config)
+	expected := map[string]string{
+		"fid":    "[fid]",
+		"diepte": `[item name="diepte" precision="0" escape="html"]`,
+		"actief": `[if name="actief" oper="eq" value="1"]In gebruik &amp; actief[/if][if name="actief" oper="eq" value="0"]Nee[/if]`,
+	}
+	for _, column := range layer.Columns {
+		if value, ok := expected[column.Name]; ok && mapserverValue(column, "html") != value {
+			t.Errorf("Value of %s was %s, expected %s", column.Name, mapserverValue(column, "html"), value)
+		}
+	}
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", []templateFeature{
+		{Values: map[string]string{"fid": "1", "diepte": "2.46", "actief": "1"}},
+	})
+	for _, value := range []string{"<td>2</td>", "<td>In gebruik &amp; actief</td>"} {
+		if !strings.Contains(rendered, value) {
+			t.Errorf("Rendered template doesn't contain %s:\n%s", value, rendered)
+		}
+	}
+}
+
+func Test_applyValueFormats_noLocale(t *testing.T) {
+	layer := newTemplateLayer("putten", []string{"diepte", "bouwdatum", "actief"}, nil)
+	layer.setColumnTypes([]columnInfo{{"diepte", "REAL"}, {"bouwdatum", "DATE"}, {"actief", "BOOLEAN"}})
+	applyValueFormats(&layer, "", nil)
+	for _, column := range layer.Columns {
+		if result := mapserverValue(column, "html"); result != "["+column.Name+"]" {
+			t.Errorf("Value of %s was %s, expected it as stored", column.Name, result)
+		}
+	}
+	if len(layer.ViewColumns) != 0 {
+		t.Errorf("Unexpected view columns: %v", layer.ViewColumns)
+	}
+	config := &templateConfig{Layers: map[string]layerConfig{"putten": {Columns: map[string]columnConfig{
+		"bouwdatum": {valueFormat: valueFormat{Date: "dd-MM-yyyy"}},
+		"actief":    {valueFormat: valueFormat{True: "In gebruik"}},
+	}}}}
+	layer = newTemplateLayer("putten", []string{"diepte", "bouwdatum", "actief"}, nil)
+	layer.setColumnTypes([]columnInfo{{"diepte", "REAL"}, {"bouwdatum", "DATE"}, {"actief", "BOOLEAN"}})
+	applyValueFormats(&layer, "", config)
+	expected := []string{
+		"[diepte]",
+		"[bouwdatum_formatted]",
+		`[if name="actief" oper="eq" value="1"]In gebruik[/if][if name="actief" oper="eq" value="0"]Nee[/if]`,
+	}
+	for i, column := range layer.Columns {
+		if result := mapserverValue(column, "html"); result != expected[i] {
+			t.Errorf("Value of %s was %s, expected %s", column.Name, result, expected[i])
+		}
+	}
+}
+
+func Test_sqliteDateExpression(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	tests := []struct {
+		pattern  string
+		value    string
+		expected string
+	}{
+		{locales["nl-NL"].Date, "2021-03-05", "05-03-2021"},
+		{locales["de-DE"].DateTime, "2021-03-05 14:07:00", "05.03.2021 14:07"},
+		{locales["en-US"].DateTime, "2021-03-05T09:07:00Z", "03/05/2021 9:07 AM"},
+		{"d-M-yy 'om' hh:mm a", "2021-03-05 00:30", "5-3-21 om 12:30 AM"},
+		{locales["nl-NL"].Date, "onbekend", "onbekend"},
+	}
+	for _, test := range tests {
+		expression, ok := sqliteDateExpression("value", test.pattern)
+		if !ok {
+			t.Fatalf("Pattern %s should be supported", test.pattern)
+		}
+		var result string
+		if err := geopackage.QueryRow("SELECT "+expression+" FROM (SELECT ? AS value)", test.value).Scan(&result); err != nil {
+			t.Fatal(err)
+		}
+		if result != test.expected {
+			t.Errorf("%s in %s was %s, expected %s", test.value, test.pattern, result, test.expected)
+		}
+	}
+	if _, ok := sqliteDateExpression("value", "d MMMM yyyy"); ok {
+		t.Error("Month names should not be supported.")
+	}
+}
+
+func Test_applyValueFormats_datePreview(t *testing.T) {
+	geopackage, cleanup := createTestGeopackage(t)
+	defer cleanup()
+	layer := newTemplateLayer("putten", []string{"naam", "bouwdatum"}, nil)
+	layer.setColumnTypes(getColumnInfoFromLayer("putten", geopackage))
+	applyValueFormats(&layer, defaultLocale, nil)
+	rendered := renderMapserverTemplate(generateHTML(layer).String(), "putten", getSampleFeatures(layer, geopackage, 1))
+	if !strings.Contains(rendered, "<td>05-01-2020</td>") {
+		t.Errorf("Rendered template doesn't contain the formatted date:\n%s", rendered)
+	}
+}
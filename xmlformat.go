@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MapServer XML template with an XSD describing the features, derived from the column types
+func xmlFormat(layer templateLayer, naming outputNaming) []generatedFile {
+	xmlName := outputFileName(naming.pattern, naming.dataset, layer.Name, "xml")
+	xsdName := outputFileName(naming.pattern, naming.dataset, layer.Name, "xsd")
+	elements := xmlElementNames(layer)
+	return []generatedFile{
+		{name: xmlName, content: []byte(generateXML(layer, elements, path.Base(xsdName)))},
+		{name: xsdName, content: []byte(generateXSD(layer, elements))},
+	}
+}
+
+// Generate an XML template for a layer, NULL values become nil elements
+func generateXML(layer templateLayer, elements []string, schemaLocation string) string {
+	log.Print("Generate XML for layer: " + layer.Name)
+	var buf strings.Builder
+	buf.WriteString("<!-- MapServer Template -->\n")
+	buf.WriteString(`[resultset layer="` + layer.Name + `"]<FeatureCollection xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="` + escapeStatic(schemaLocation, "xml") + `"` + xmlLangAttribute(layer.Language) + ">\n")
+	buf.WriteString("[feature]\t<" + ncName(layer.Name) + ">\n")
+	for i, column := range layer.Columns {
+		element := elements[i]
+		value := "\t\t<" + element + ">" + mapserverValue(column.unformatted(), "xml") + "</" + element + ">"
+		if column.Name != "" && column.Tag == "" {
+			buf.WriteString(`[if name="` + column.Name + `" oper="isnull"]` + "\t\t<" + element + ` xsi:nil="true"/>` + "\n[/if]")
+			buf.WriteString(`[if name="` + column.Name + `" oper="isset"]` + value + "\n[/if]")
+		} else {
+			buf.WriteString(value + "\n")
+		}
+	}
+	buf.WriteString("\t</" + ncName(layer.Name) + ">\n[/feature]</FeatureCollection>\n[/resultset]")
+	return buf.String()
+}
+
+// xml:lang attribute for the language of the template, empty without a language
+func xmlLangAttribute(language string) string {
+	if language == "" {
+		return ""
+	}
+	return ` xml:lang="` + language + `"`
+}
+
+// Generate an XSD for the XML template of a layer, the collection may have the xml:lang of a translated template
+func generateXSD(layer templateLayer, elements []string) string {
+	log.Print("Generate XSD for layer: " + layer.Name)
+	var buf strings.Builder
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	buf.WriteString("<xs:schema xmlns:xs=\"http://www.w3.org/2001/XMLSchema\" elementFormDefault=\"qualified\">\n")
+	buf.WriteString("\t<xs:import namespace=\"http://www.w3.org/XML/1998/namespace\" schemaLocation=\"http://www.w3.org/2001/xml.xsd\"/>\n")
+	buf.WriteString("\t<xs:element name=\"FeatureCollection\">\n\t\t<xs:complexType>\n\t\t\t<xs:sequence>\n")
+	buf.WriteString("\t\t\t\t<xs:element name=\"" + ncName(layer.Name) + "\" minOccurs=\"0\" maxOccurs=\"unbounded\">\n")
+	buf.WriteString("\t\t\t\t\t<xs:complexType>\n\t\t\t\t\t\t<xs:sequence>\n")
+	for i, column := range layer.Columns {
+		buf.WriteString("\t\t\t\t\t\t\t<xs:element name=\"" + elements[i] + "\" type=\"" + xsdType(column) + "\"")
+		if column.Name != "" && column.Tag == "" {
+			buf.WriteString(" nillable=\"true\"")
+		}
+		if column.Header != elements[i] {
+			buf.WriteString(">\n\t\t\t\t\t\t\t\t<xs:annotation><xs:documentation>" + escapeStatic(column.Header, "xml") + "</xs:documentation></xs:annotation>\n")
+			buf.WriteString("\t\t\t\t\t\t\t</xs:element>\n")
+		} else {
+			buf.WriteString("/>\n")
+		}
+	}
+	buf.WriteString("\t\t\t\t\t\t</xs:sequence>\n\t\t\t\t\t</xs:complexType>\n\t\t\t\t</xs:element>\n")
+	buf.WriteString("\t\t\t</xs:sequence>\n\t\t\t<xs:attribute ref=\"xml:lang\"/>\n\t\t</xs:complexType>\n\t</xs:element>\n</xs:schema>\n")
+	return buf.String()
+}
+
+// XML Schema type of a column, from the column type declared in the Geopackage
+func xsdType(column templateColumn) string {
+	if column.Name == "" || column.Tag != "" {
+		return "xs:string"
+	}
+	columnType := strings.ToUpper(column.Type)
+	switch {
+	case isBooleanType(columnType):
+		return "xs:boolean"
+	case strings.Contains(columnType, "INT"):
+		return "xs:integer"
+	case strings.Contains(columnType, "NUMERIC"), strings.Contains(columnType, "DECIMAL"):
+		return "xs:decimal"
+	case isNumericType(columnType):
+		return "xs:double"
+	case columnType == "DATETIME":
+		return "xs:dateTime"
+	case columnType == "DATE":
+		return "xs:date"
+	}
+	return "xs:string"
+}
+
+// Unique element names for the columns of a layer
+func xmlElementNames(layer templateLayer) []string {
+	used := make(map[string]bool)
+	var names []string
+	for _, column := range layer.Columns {
+		name := column.Name
+		if name == "" {
+			name = column.Header
+		}
+		name = ncName(name)
+		unique := name
+		for i := 2; used[unique]; i++ {
+			unique = name + "_" + strconv.Itoa(i)
+		}
+		used[unique] = true
+		names = append(names, unique)
+	}
+	return names
+}
+
+// Turn a name into a valid XML NCName: letters, digits, '.', '-' and '_', not starting with a digit, '.' or '-'
+func ncName(name string) string {
+	var buf strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			buf.WriteRune(r)
+		} else {
+			buf.WriteRune('_')
+		}
+	}
+	result := buf.String()
+	if result == "" {
+		return "_"
+	}
+	if first := []rune(result)[0]; !unicode.IsLetter(first) && first != '_' {
+		result = "_" + result
+	}
+	if strings.HasPrefix(strings.ToLower(result), "xml") {
+		result = "_" + result
+	}
+	return result
+}
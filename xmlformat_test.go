@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func Test_ncName(t *testing.T) {
+	tests := map[string]string{
+		"naam":          "naam",
+		"diepte (m)":    "diepte__m_",
+		"1e_meting":     "_1e_meting",
+		"xml_id":        "_xml_id",
+		"straatnaam.nl": "straatnaam.nl",
+		"":              "_",
+		"-waarde":       "_-waarde",
+		"één":           "één",
+	}
+	for name, expected := range tests {
+		if result := ncName(name); result != expected {
+			t.Errorf("ncName(%q) = %q, expected %q", name, result, expected)
+		}
+	}
+}
+
+func Test_xmlElementNames(t *testing.T) {
+	layer := templateLayer{Columns: []templateColumn{{Name: "a b"}, {Name: "a_b"}, {Header: "a-b"}}}
+	result := strings.Join(xmlElementNames(layer), ",")
+	if result != "a_b,a_b_2,a-b" {
+		t.Errorf("Result was not OK: %s", result)
+	}
+}
+
+func Test_generateXML(t *testing.T) {
+	layer := newTemplateLayer("putten", []string{"fid", "geom", "naam", "diepte"}, []string{"geom"})
+	layer.setColumnTypes([]columnInfo{{"fid", "INTEGER"}, {"naam", "TEXT"}, {"diepte", "REAL"}})
+	elements := xmlElementNames(layer)
+	features := []templateFeature{
+		{Values: map[string]string{"fid": "1", "naam": "Put <1> & co", "diepte": "2.5"}},
+		{Values: map[string]string{"fid": "2", "naam": "Put 2", "diepte": ""}},
+	}
+	rendered := renderMapserverTemplate(generateXML(layer, elements, "putten.xsd"), "putten", features)
+	var result struct {
+		Features []struct {
+			Fid    string `xml:"fid"`
+			Naam   string `xml:"naam"`
+			Diepte struct {
+				Value string `xml:",chardata"`
+				Nil   string `xml:"http://www.w3.org/2001/XMLSchema-instance nil,attr"`
+			} `xml:"diepte"`
+		} `xml:"putten"`
+	}
+	if err := xml.Unmarshal([]byte(rendered), &result); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, rendered)
+	}
+	if len(result.Features) != 2 || result.Features[0].Naam != "Put <1> & co" || result.Features[0].Diepte.Value != "2.5" || result.Features[1].Diepte.Nil != "true" {
+		t.Errorf("Unexpected XML:\n%s", rendered)
+	}
+
+	xsd := generateXSD(layer, elements)
+	if err := xml.Unmarshal([]byte(xsd), new(interface{})); err != nil {
+		t.Fatalf("Invalid XSD: %v\n%s", err, xsd)
+	}
+	for _, expected := range []string{`name="fid" type="xs:integer" nillable="true"`, `name="naam" type="xs:string"`, `name="diepte" type="xs:double"`, `<xs:attribute ref="xml:lang"/>`} {
+		if !strings.Contains(xsd, expected) {
+			t.Errorf("XSD doesn't contain %s:\n%s", expected, xsd)
+		}
+	}
+}